@@ -1,12 +1,26 @@
 package attestation
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+
+	ssldsse "github.com/secure-systems-lab/go-securesystemslib/dsse"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/generate"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/options"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/sign"
 	"github.com/sigstore/cosign/v2/cmd/cosign/cli/verify"
+	sigs "github.com/sigstore/cosign/v2/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Attestation controls the sining and verification of resources.
@@ -22,8 +36,15 @@ type Attestation struct {
 	offline    bool // offline verification
 	ignoreSCT  bool // ignore embedded SCT proof
 	ignoreTlog bool // ignore transaction log
+
+	keyFile     string            // temporary file holding the inline public key
+	annotations map[string]string // annotations expected on the verified resources
 }
 
+// ErrAnnotationMismatch marks a verified resource without the annotations expected by the
+// attestation.
+var ErrAnnotationMismatch = errors.New("annotation mismatch")
+
 // GetPass prompts for the user private-key password only once, when the password is already
 // stored it returns instead.
 func (a *Attestation) GetPass(confirm bool) ([]byte, error) {
@@ -50,8 +71,55 @@ func (a *Attestation) Sign(payloadPath, outputSignature string) error {
 	return err
 }
 
-// Verify verifies the resource signature.
+// SignEnvelope signs the payload into a DSSE envelope, using the attestation private key. The
+// envelope is returned JSON encoded.
+func (a *Attestation) SignEnvelope(ctx context.Context, payloadType string, payload []byte) ([]byte, error) {
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", a.keyOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer sv.Close()
+	return dsse.WrapSigner(sv, payloadType).SignMessage(bytes.NewReader(payload))
+}
+
+// SignMessage signs the message with the attestation private key, returns the raw signature.
+func (a *Attestation) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	sv, err := sign.SignerFromKeyOpts(ctx, "", "", a.keyOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer sv.Close()
+	return sv.SignMessage(bytes.NewReader(message))
+}
+
+// verifyMessage verifies the raw message signature with the attestation public key.
+func (a *Attestation) verifyMessage(ctx context.Context, message, signature []byte) error {
+	verifier, err := sigs.PublicKeyFromKeyRef(ctx, a.keyOpts.KeyRef)
+	if err != nil {
+		return err
+	}
+	return verifier.VerifySignature(bytes.NewReader(signature), bytes.NewReader(message))
+}
+
+// verifyEnvelope verifies the DSSE envelope signature with the attestation public key.
+func (a *Attestation) verifyEnvelope(ctx context.Context, envelope []byte) error {
+	verifier, err := sigs.PublicKeyFromKeyRef(ctx, a.keyOpts.KeyRef)
+	if err != nil {
+		return err
+	}
+	return dsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(envelope), nil)
+}
+
+// Verify verifies the resource signature, and the resource annotations when expected.
 func (a *Attestation) Verify(ctx context.Context, blobRef, sigRef string) error {
+	if err := a.verifySignature(ctx, blobRef, sigRef); err != nil {
+		return err
+	}
+	return a.verifyAnnotations(blobRef)
+}
+
+// verifySignature verifies the resource signature with the attestation key.
+func (a *Attestation) verifySignature(ctx context.Context, blobRef, sigRef string) error {
 	v := verify.VerifyBlobCmd{
 		KeyOpts:    a.keyOpts,
 		SigRef:     sigRef,
@@ -62,9 +130,86 @@ func (a *Attestation) Verify(ctx context.Context, blobRef, sigRef string) error
 	return v.Exec(ctx, blobRef)
 }
 
+// verifyAnnotations asserts the resource carries the expected annotations, the resource being
+// signed its annotations are trusted once the signature is verified.
+func (a *Attestation) verifyAnnotations(blobRef string) error {
+	if len(a.annotations) == 0 {
+		return nil
+	}
+	payload, err := os.ReadFile(blobRef)
+	if err != nil {
+		return err
+	}
+	return VerifyAnnotations(blobRef, payload, a.annotations)
+}
+
+// VerifyAnnotations asserts the resource payload carries the expected annotations, the name
+// identifies the resource on the error message. The payload must hold a single resource, only
+// the first YAML document would be verified otherwise.
+func VerifyAnnotations(name string, payload []byte, annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+	if err := resource.SingleDocument(payload); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	obj, err := resource.Decode(payload)
+	if err != nil {
+		return err
+	}
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("%w: %s is not a Kubernetes object", ErrAnnotationMismatch, name)
+	}
+	actual := o.GetAnnotations()
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v, ok := actual[k]; !ok || v != annotations[k] {
+			return fmt.Errorf("%w: %s expects annotation %s=%q, got %q", ErrAnnotationMismatch, name, k, annotations[k], v)
+		}
+	}
+	return nil
+}
+
+// WithAnnotations sets the annotations expected on the verified resources.
+func (a *Attestation) WithAnnotations(annotations map[string]string) *Attestation {
+	a.annotations = annotations
+	return a
+}
+
+// Close removes the temporary file holding the inline public key.
+func (a *Attestation) Close() error {
+	if a.keyFile == "" {
+		return nil
+	}
+	return os.Remove(a.keyFile)
+}
+
 // NewAttestation instantiate the Attestation helper setting the default parameters expected
-// for signing and verifying resources.
+// for signing and verifying resources. The key is either a key reference (file location, KMS
+// URI, …) or the inline PEM public key, stored on a temporary file until Close is called.
 func NewAttestation(key string) (*Attestation, error) {
+	keyFile := ""
+	if InlineKey(key) {
+		f, err := os.CreateTemp("", "catalog-cd-*.pub")
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.WriteString(key)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+			return nil, err
+		}
+		keyFile, key = f.Name(), f.Name()
+	}
+
 	o := &options.SignBlobOptions{}
 	oidcClientSecret, err := o.OIDC.ClientSecret()
 	if err != nil {
@@ -92,8 +237,115 @@ func NewAttestation(key string) (*Attestation, error) {
 		offline:           true,
 		outputCertificate: "",
 		tlogUpload:        false,
+		keyFile:           keyFile,
 	}
 	a.keyOpts.PassFunc = a.GetPass
 
 	return a, nil
 }
+
+// InlineKey asserts the key is the PEM encoded public key, instead of a key reference (file
+// location, KMS URI, …).
+func InlineKey(key string) bool {
+	return strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN")
+}
+
+// VerifyBlob verifies the payload signature with the informed public key, either a key
+// reference or the inline PEM.
+func VerifyBlob(ctx context.Context, key string, payload, signature []byte) error {
+	return VerifyBlobWithKeys(ctx, []string{key}, nil, payload, signature)
+}
+
+// VerifyBlobWithKeys verifies the payload signature with the candidate keys, as VerifyWithKeys.
+// The payload and signature are stored on a temporary folder for the verification.
+func VerifyBlobWithKeys(ctx context.Context, keys []string, annotations map[string]string, payload, signature []byte) error {
+	dir, err := os.MkdirTemp("", "catalog-cd-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	blobRef := filepath.Join(dir, "blob")
+	sigRef := filepath.Join(dir, "blob.sig")
+	if err := os.WriteFile(blobRef, payload, 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(sigRef, signature, 0o600); err != nil {
+		return err
+	}
+	return VerifyWithKeys(ctx, keys, annotations, blobRef, sigRef)
+}
+
+// VerifyWithKeys verifies the resource signature with the first of the candidate keys matching
+// it, rotated keys are all tried. The expected annotations are checked once the signature is
+// verified.
+func VerifyWithKeys(ctx context.Context, keys []string, annotations map[string]string, blobRef, sigRef string) error {
+	errs := []error{}
+	for _, key := range keys {
+		a, err := NewAttestation(key)
+		if err != nil {
+			return err
+		}
+		err = a.WithAnnotations(annotations).verifySignature(ctx, blobRef, sigRef)
+		if err == nil {
+			err = a.verifyAnnotations(blobRef)
+			a.Close()
+			return err
+		}
+		a.Close()
+		errs = append(errs, err)
+	}
+	if len(keys) > 1 {
+		return fmt.Errorf("none of the %d keys verifies the signature: %w", len(keys), errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// VerifyEnvelopeWithKeys verifies the DSSE envelope with the first of the candidate keys matching
+// one of its signatures, and returns the envelope payload type and decoded payload.
+func VerifyEnvelopeWithKeys(ctx context.Context, keys []string, envelope []byte) (string, []byte, error) {
+	env := ssldsse.Envelope{}
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return "", nil, fmt.Errorf("invalid DSSE envelope: %w", err)
+	}
+	errs := []error{}
+	for _, key := range keys {
+		a, err := NewAttestation(key)
+		if err != nil {
+			return "", nil, err
+		}
+		err = a.verifyEnvelope(ctx, envelope)
+		a.Close()
+		if err == nil {
+			payload, err := env.DecodeB64Payload()
+			return env.PayloadType, payload, err
+		}
+		errs = append(errs, err)
+	}
+	if len(keys) > 1 {
+		return "", nil, fmt.Errorf("none of the %d keys verifies the envelope: %w", len(keys), errors.Join(errs...))
+	}
+	return "", nil, errors.Join(errs...)
+}
+
+// VerifyMessageWithKeys verifies the raw message signature with the first of the candidate keys
+// matching it.
+func VerifyMessageWithKeys(ctx context.Context, keys []string, message, signature []byte) error {
+	errs := []error{}
+	for _, key := range keys {
+		a, err := NewAttestation(key)
+		if err != nil {
+			return err
+		}
+		err = a.verifyMessage(ctx, message, signature)
+		a.Close()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	if len(keys) > 1 {
+		return fmt.Errorf("none of the %d keys verifies the signature: %w", len(keys), errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package attestation_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const task = `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+  annotations:
+    team: tekton-ecosystem
+spec:
+  steps:
+    - name: foo
+      image: registry.access.redhat.com/ubi9/ubi-minimal
+`
+
+// newKey generates an ECDSA key, returns the PEM encoded public key and the base64 signature
+// of the payload.
+func newKey(t *testing.T, payload string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	digest := sha256.Sum256([]byte(payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NilError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestVerifyWithKeys(t *testing.T) {
+	current, signature := newKey(t, task)
+	previous, _ := newKey(t, task)
+	dir := fs.NewDir(t, "attestation",
+		fs.WithFile("task.yaml", task),
+		fs.WithFile("task.yaml.sig", signature),
+		fs.WithFile("previous.pub", previous))
+	defer dir.Remove()
+	blobRef, sigRef := dir.Join("task.yaml"), dir.Join("task.yaml.sig")
+	ctx := context.Background()
+
+	// rotated keys are all tried, inline or not
+	assert.NilError(t, attestation.VerifyWithKeys(ctx, []string{dir.Join("previous.pub"), current}, nil, blobRef, sigRef))
+	err := attestation.VerifyWithKeys(ctx, []string{dir.Join("previous.pub"), previous}, nil, blobRef, sigRef)
+	assert.ErrorContains(t, err, "none of the 2 keys verifies the signature")
+
+	// annotations are checked once the signature is verified
+	assert.NilError(t, attestation.VerifyWithKeys(ctx, []string{current},
+		map[string]string{"team": "tekton-ecosystem"}, blobRef, sigRef))
+	err = attestation.VerifyWithKeys(ctx, []string{previous, current},
+		map[string]string{"team": "other"}, blobRef, sigRef)
+	assert.ErrorIs(t, err, attestation.ErrAnnotationMismatch)
+	assert.ErrorContains(t, err, `expects annotation team="other", got "tekton-ecosystem"`)
+}
+
+func TestNewAttestationInlineKey(t *testing.T) {
+	key, _ := newKey(t, task)
+	a, err := attestation.NewAttestation(key)
+	assert.NilError(t, err)
+	assert.NilError(t, a.Close())
+	assert.Assert(t, attestation.InlineKey(key))
+	assert.Assert(t, !attestation.InlineKey("cosign.pub"))
+}
+
+func TestVerifyAnnotationsMultipleDocuments(t *testing.T) {
+	annotations := map[string]string{"team": "tekton-ecosystem"}
+	assert.NilError(t, attestation.VerifyAnnotations("task.yaml", []byte(task), annotations))
+
+	// the second resource isn't annotated
+	payload := task + "---\napiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: bar\n"
+	err := attestation.VerifyAnnotations("task.yaml", []byte(payload), annotations)
+	assert.ErrorIs(t, err, resource.ErrMultipleDocuments)
+	assert.ErrorContains(t, err, "task.yaml: several YAML documents")
+}
@@ -0,0 +1,377 @@
+// Package audit verifies a remote release, its contract, resources tarball and every resource
+// checksum and signature, without extracting the resources on disk.
+package audit
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
+)
+
+// Status of a check.
+const (
+	StatusPass = "pass" // the check succeeded
+	StatusFail = "fail" // the check failed
+	StatusSkip = "skip" // the check doesn't apply, for instance the tarball checksum is not recorded
+)
+
+// Check kinds.
+const (
+	CheckDownload  = "download"
+	CheckChecksum  = "checksum"
+	CheckSignature = "signature"
+	CheckExtract   = "extract"
+)
+
+// DefaultMaxBytes maximum amount of bytes read from the resources tarball.
+const DefaultMaxBytes = 100 << 20
+
+// Check a single verification of the release.
+type Check struct {
+	Subject string `json:"subject"` // contract, tarball or resource filename
+	Check   string `json:"check"`   // download, checksum, signature or extract
+	Status  string `json:"status"`  // pass, fail or skip
+	Message string `json:"message,omitempty"`
+}
+
+// Report lists every check of the release, in order.
+type Report struct {
+	Version   string  `json:"version,omitempty"`
+	Contract  string  `json:"contract"`
+	Resources string  `json:"resources"`
+	Checks    []Check `json:"checks"`
+}
+
+// Failed returns the amount of failed checks.
+func (r Report) Failed() int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTable renders the report as a table, followed by the summary.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUBJECT\tCHECK\tSTATUS\tMESSAGE")
+	for _, c := range r.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Subject, c.Check, strings.ToUpper(c.Status), c.Message)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d checks, %d failed\n", len(r.Checks), r.Failed())
+	return err
+}
+
+// WriteJSON renders the report as indented JSON.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// add records the check, the error message marks it failed.
+func (r *Report) add(subject, check string, err error) {
+	if err != nil {
+		r.Checks = append(r.Checks, Check{Subject: subject, Check: check, Status: StatusFail, Message: err.Error()})
+		return
+	}
+	r.Checks = append(r.Checks, Check{Subject: subject, Check: check, Status: StatusPass})
+}
+
+// skip records the check as not applicable.
+func (r *Report) skip(subject, check, message string) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Check: check, Status: StatusSkip, Message: message})
+}
+
+// addDetached records the detached signature check of the subject, skipped when the release
+// doesn't publish the signature, unless it's required.
+func (r *Report) addDetached(subject string, err error, required bool) {
+	if errors.Is(err, errNoSignature) && !required {
+		r.skip(subject, CheckSignature, err.Error())
+		return
+	}
+	r.add(subject, CheckSignature, err)
+}
+
+// Release locations of the remote release assets.
+type Release struct {
+	Version               string
+	ContractURL           string
+	ContractSignatureURL  string
+	ResourcesURL          string
+	ResourcesSignatureURL string
+	PublishedAt           time.Time
+}
+
+// ReleaseFromContractURL returns the release assets next to the contract, the tarball uses the
+// default name.
+func ReleaseFromContractURL(contractURL string) (Release, error) {
+	u, err := url.Parse(contractURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return Release{}, fmt.Errorf("invalid contract URL %q", contractURL)
+	}
+	resources := *u
+	resources.Path = path.Join(path.Dir(u.Path), contract.ResourcesName)
+	return Release{
+		ContractURL:           contractURL,
+		ContractSignatureURL:  contract.SignatureFile(contractURL),
+		ResourcesURL:          resources.String(),
+		ResourcesSignatureURL: contract.SignatureFile(resources.String()),
+	}, nil
+}
+
+// ReleaseFromRepository looks up the release of the repository tagged with the informed
+// version, whatever its channel. The version may omit the "v" prefix.
+func ReleaseFromRepository(ctx context.Context, r config.Repository, clients *fetcher.Clients, version string) (Release, error) {
+	if r.CatalogName == "" {
+		r.CatalogName = contract.Filename
+	}
+	if r.ResourcesTarballName == "" {
+		r.ResourcesTarballName = contract.ResourcesName
+	}
+	r.Channel = config.ChannelAll
+	source, err := fetcher.NewSource(r, clients)
+	if err != nil {
+		return Release{}, err
+	}
+	releases, err := fetcher.ListReleases(ctx, r, source)
+	if err != nil {
+		return Release{}, err
+	}
+	for _, release := range releases {
+		if release.Version == version || strings.TrimPrefix(release.Version, "v") == strings.TrimPrefix(version, "v") {
+			return Release{
+				Version:               release.Version,
+				ContractURL:           release.ContractURL,
+				ContractSignatureURL:  release.ContractSignatureURL,
+				ResourcesURL:          release.ResourcesURL,
+				ResourcesSignatureURL: release.ResourcesSignatureURL,
+				PublishedAt:           release.PublishedAt,
+			}, nil
+		}
+	}
+	return Release{}, fmt.Errorf("release %s not found on %s (with %s and %s assets)",
+		version, r.URL, r.CatalogName, r.ResourcesTarballName)
+}
+
+// Options of the release verification.
+type Options struct {
+	// PublicKey trusted public key (key reference or inline PEM), by default the contract
+	// attestation keys valid when the release was published are used.
+	PublicKey string
+	// At release time selecting the contract keys, by default the release publication time.
+	At time.Time
+	// MaxBytes maximum amount of bytes read from the resources tarball, DefaultMaxBytes when
+	// zero.
+	MaxBytes int64
+	// Clients downloads the release assets with the credentials of the host, the default
+	// transport without credentials when nil.
+	Clients *fetcher.Clients
+	// RequireContractSignature the contract detached signature must be published, otherwise
+	// the detached signatures are verified when published and skipped when not.
+	RequireContractSignature bool
+}
+
+// Verify downloads the release contract and tarball and checks the tarball and every resource
+// checksum and signature, every check is reported instead of stopping on the first failure.
+// Errors out only when the contract can't be downloaded.
+func Verify(ctx context.Context, release Release, opts Options) (Report, error) {
+	report := Report{Version: release.Version, Contract: release.ContractURL, Resources: release.ResourcesURL}
+	if opts.At.IsZero() {
+		opts.At = release.PublishedAt
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+
+	payload, err := download(ctx, opts.Clients, release.ContractURL, opts.MaxBytes)
+	if err != nil {
+		return report, fmt.Errorf("could not download contract %s: %w", release.ContractURL, err)
+	}
+	c, err := contract.NewContractFromData(payload)
+	if err != nil {
+		return report, fmt.Errorf("invalid contract %s: %w", release.ContractURL, err)
+	}
+	keys := func(keyID string) ([]string, error) {
+		if opts.PublicKey != "" {
+			return []string{opts.PublicKey}, nil
+		}
+		return c.GetPublicKeys(keyID, opts.At)
+	}
+
+	// the contract and the tarball carry detached signatures, when published
+	report.addDetached("contract", verifyDetached(ctx, opts.Clients, payload, release.ContractSignatureURL, keys, opts.MaxBytes),
+		opts.RequireContractSignature)
+
+	tarball, err := download(ctx, opts.Clients, release.ResourcesURL, opts.MaxBytes)
+	report.add("tarball", CheckDownload, err)
+	if err != nil {
+		return report, nil
+	}
+	if c.Catalog.Tarball == nil || c.Catalog.Tarball.Checksum == "" {
+		report.skip("tarball", CheckChecksum, "checksum not recorded on the contract")
+	} else {
+		report.add("tarball", CheckChecksum, verifyChecksum(tarball, c.Catalog.Tarball.Checksum))
+	}
+	report.addDetached("tarball", verifyDetached(ctx, opts.Clients, tarball, release.ResourcesSignatureURL, keys, opts.MaxBytes), false)
+
+	files, err := readTarball(tarball, opts.MaxBytes)
+	if err != nil {
+		report.add("tarball", CheckExtract, err)
+		return report, nil
+	}
+	for _, r := range c.Catalog.Resources.All() {
+		file, ok := files[path.Clean(r.Filename)]
+		if !ok {
+			err := fmt.Errorf("%s is not part of the resources tarball", r.Filename)
+			report.add(r.Filename, CheckChecksum, err)
+			report.add(r.Filename, CheckSignature, err)
+			continue
+		}
+		report.add(r.Filename, CheckChecksum, verifyChecksum(file, r.Checksum))
+		report.add(r.Filename, CheckSignature, verifyResource(ctx, c, r, file, files, keys))
+	}
+	return report, nil
+}
+
+// download reads the asset on the informed location, up to maxBytes.
+func download(ctx context.Context, clients *fetcher.Clients, uri string, maxBytes int64) ([]byte, error) {
+	rc, err := clients.Open(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	payload, err := io.ReadAll(io.LimitReader(rc, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(payload)) > maxBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", uri, maxBytes)
+	}
+	return payload, nil
+}
+
+// verifyChecksum compares the payload SHA256 sum with the expected one.
+func verifyChecksum(payload []byte, expected string) error {
+	sum := sha256.Sum256(payload)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("sha256 %s, expected %s", actual, expected)
+	}
+	return nil
+}
+
+// errNoSignature marks a detached signature the release doesn't publish.
+var errNoSignature = errors.New("no detached signature")
+
+// verifyDetached verifies the payload against the detached signature on the informed location,
+// errNoSignature when the release doesn't publish it.
+func verifyDetached(ctx context.Context, clients *fetcher.Clients, payload []byte, signatureURL string, keys func(string) ([]string, error), maxBytes int64) error {
+	if signatureURL == "" {
+		return errNoSignature
+	}
+	signature, err := download(ctx, clients, signatureURL, maxBytes)
+	if errors.Is(err, fetcher.ErrAssetNotFound) {
+		return fmt.Errorf("%w, %s not found", errNoSignature, signatureURL)
+	}
+	if err != nil {
+		return fmt.Errorf("could not download signature %s: %w", signatureURL, err)
+	}
+	candidates, err := keys("")
+	if err != nil {
+		return err
+	}
+	return attestation.VerifyBlobWithKeys(ctx, candidates, nil, payload, signature)
+}
+
+// verifyResource verifies the resource signature, either a file of the tarball, the payload
+// informed by the contract or the signature embedded on the resource, and the annotations expected by the contract attestation.
+func verifyResource(
+	ctx context.Context,
+	c *contract.Contract,
+	r *contract.TektonResource,
+	payload []byte,
+	files map[string][]byte,
+	keys func(string) ([]string, error),
+) error {
+	var signature []byte
+	switch file, ok := files[path.Clean(r.Signature)]; {
+	case r.Signature == "":
+		// the Tekton trusted resources signature, embedded on the resource
+		candidates, err := keys(r.KeyID)
+		if err != nil {
+			return err
+		}
+		err = trustedresources.VerifyPayload(ctx, candidates, c.GetAnnotations(), payload)
+		if errors.Is(err, trustedresources.ErrNotSigned) {
+			return errors.New("not signed")
+		}
+		return err
+	case ok:
+		signature = file
+	case path.Ext(r.Signature) == "."+contract.SignatureExtension:
+		return fmt.Errorf("signature %s is not part of the resources tarball", r.Signature)
+	default:
+		signature = []byte(r.Signature)
+	}
+	candidates, err := keys(r.KeyID)
+	if err != nil {
+		return err
+	}
+	return attestation.VerifyBlobWithKeys(ctx, candidates, c.GetAnnotations(), payload, signature)
+}
+
+// readTarball reads the regular files of the tarball in memory, keyed by their clean name.
+func readTarball(payload []byte, maxBytes int64) (map[string][]byte, error) {
+	gzr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	files := map[string][]byte{}
+	size := int64(0)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		size += header.Size
+		if header.Size < 0 || size > maxBytes {
+			return nil, fmt.Errorf("more than %d bytes once extracted", maxBytes)
+		}
+		file, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(header.Name)] = file
+	}
+}
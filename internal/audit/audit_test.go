@@ -0,0 +1,214 @@
+package audit_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/audit"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"gotest.tools/v3/assert"
+)
+
+const (
+	fooTask = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+	barTask = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: bar\n"
+)
+
+// signer signs payloads with a generated ECDSA key.
+type signer struct {
+	key       *ecdsa.PrivateKey
+	publicKey string // PEM encoded public key
+}
+
+func newSigner(t *testing.T) signer {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	return signer{key: key, publicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}
+}
+
+func (s signer) sign(t *testing.T, payload []byte) string {
+	t.Helper()
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	assert.NilError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func checksum(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// tarball builds the resources tarball holding the informed files.
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, name := range []string{"tasks/foo/foo.yaml", "tasks/foo/foo.yaml.sig", "tasks/bar/bar.yaml"} {
+		content, ok := files[name]
+		if !ok {
+			continue
+		}
+		assert.NilError(t, tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, tw.Close())
+	assert.NilError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+// newReleaseServer serves a release signed by the informed signer, "bar" is tampered with after
+// the release.
+func newReleaseServer(t *testing.T, s signer) *httptest.Server {
+	t.Helper()
+	resources := tarball(t, map[string]string{
+		"tasks/foo/foo.yaml":     fooTask,
+		"tasks/foo/foo.yaml.sig": s.sign(t, []byte(fooTask)),
+		"tasks/bar/bar.yaml":     strings.ReplaceAll(barTask, "bar", "tampered"),
+	})
+	c := contract.NewContractEmpty()
+	c.Catalog.Resources.Tasks = []*contract.TektonResource{{
+		Name:      "foo",
+		Filename:  "tasks/foo/foo.yaml",
+		Checksum:  checksum([]byte(fooTask)),
+		Signature: "tasks/foo/foo.yaml.sig",
+	}, {
+		Name:      "bar",
+		Filename:  "tasks/bar/bar.yaml",
+		Checksum:  checksum([]byte(barTask)),
+		Signature: s.sign(t, []byte(barTask)),
+	}, {
+		Name:     "missing",
+		Filename: "tasks/missing/missing.yaml",
+	}}
+	c.Catalog.Tarball = &contract.Tarball{Name: contract.ResourcesName, Checksum: checksum(resources)}
+	payload, err := c.Print()
+	assert.NilError(t, err)
+
+	assets := map[string][]byte{
+		"/v0.1.0/catalog.yaml":         payload,
+		"/v0.1.0/catalog.yaml.sig":     []byte(s.sign(t, payload)),
+		"/v0.1.0/resources.tar.gz":     resources,
+		"/v0.1.0/resources.tar.gz.sig": []byte(s.sign(t, resources)),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asset, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(asset)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVerify(t *testing.T) {
+	s := newSigner(t)
+	server := newReleaseServer(t, s)
+
+	release, err := audit.ReleaseFromContractURL(server.URL + "/v0.1.0/catalog.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, release.ResourcesURL, server.URL+"/v0.1.0/resources.tar.gz")
+
+	report, err := audit.Verify(context.Background(), release, audit.Options{PublicKey: s.publicKey})
+	assert.NilError(t, err)
+
+	status := map[string]string{}
+	for _, c := range report.Checks {
+		status[c.Subject+" "+c.Check] = c.Status
+	}
+	assert.DeepEqual(t, status, map[string]string{
+		"contract signature":                   audit.StatusPass,
+		"tarball download":                     audit.StatusPass,
+		"tarball checksum":                     audit.StatusPass,
+		"tarball signature":                    audit.StatusPass,
+		"tasks/foo/foo.yaml checksum":          audit.StatusPass,
+		"tasks/foo/foo.yaml signature":         audit.StatusPass,
+		"tasks/bar/bar.yaml checksum":          audit.StatusFail,
+		"tasks/bar/bar.yaml signature":         audit.StatusFail,
+		"tasks/missing/missing.yaml checksum":  audit.StatusFail,
+		"tasks/missing/missing.yaml signature": audit.StatusFail,
+	})
+	assert.Equal(t, report.Failed(), 4)
+
+	var table bytes.Buffer
+	assert.NilError(t, report.WriteTable(&table))
+	assert.Assert(t, strings.Contains(table.String(), "10 checks, 4 failed"))
+	assert.Assert(t, strings.Contains(table.String(), "tasks/missing/missing.yaml is not part of the resources tarball"))
+
+	var j bytes.Buffer
+	assert.NilError(t, report.WriteJSON(&j))
+	assert.Assert(t, strings.Contains(j.String(), fmt.Sprintf(`"contract": %q`, release.ContractURL)))
+}
+
+func TestVerifyUntrustedKey(t *testing.T) {
+	server := newReleaseServer(t, newSigner(t))
+	release, err := audit.ReleaseFromContractURL(server.URL + "/v0.1.0/catalog.yaml")
+	assert.NilError(t, err)
+
+	// every signature fails, the checksums are still checked
+	report, err := audit.Verify(context.Background(), release, audit.Options{PublicKey: newSigner(t).publicKey})
+	assert.NilError(t, err)
+	for _, c := range report.Checks {
+		if c.Check == audit.CheckSignature {
+			assert.Equal(t, c.Status, audit.StatusFail, c.Subject)
+		}
+	}
+	assert.Equal(t, report.Failed(), 7)
+}
+
+func TestVerifyContractNotFound(t *testing.T) {
+	server := newReleaseServer(t, newSigner(t))
+	release, err := audit.ReleaseFromContractURL(server.URL + "/v0.2.0/catalog.yaml")
+	assert.NilError(t, err)
+	_, err = audit.Verify(context.Background(), release, audit.Options{})
+	assert.ErrorContains(t, err, "could not download contract")
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	s := newSigner(t)
+	server := newReleaseServer(t, s)
+	release, err := audit.ReleaseFromContractURL(server.URL + "/v0.1.0/catalog.yaml")
+	assert.NilError(t, err)
+
+	// the release doesn't publish the detached signatures, their checks are skipped
+	release.ContractSignatureURL = ""
+	release.ResourcesSignatureURL = server.URL + "/v0.1.0/missing.tar.gz.sig"
+	report, err := audit.Verify(context.Background(), release, audit.Options{PublicKey: s.publicKey})
+	assert.NilError(t, err)
+	status := map[string]string{}
+	for _, c := range report.Checks {
+		status[c.Subject+" "+c.Check] = c.Status
+	}
+	assert.Equal(t, status["contract signature"], audit.StatusSkip)
+	assert.Equal(t, status["tarball signature"], audit.StatusSkip)
+	assert.Equal(t, report.Failed(), 4)
+
+	// unless the contract signature is required
+	report, err = audit.Verify(context.Background(), release, audit.Options{PublicKey: s.publicKey, RequireContractSignature: true})
+	assert.NilError(t, err)
+	assert.Equal(t, report.Checks[0].Subject+" "+report.Checks[0].Check, "contract signature")
+	assert.Equal(t, report.Checks[0].Status, audit.StatusFail)
+	assert.Equal(t, report.Failed(), 5)
+}
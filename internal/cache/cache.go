@@ -0,0 +1,315 @@
+// Package cache implements a content-addressed, on-disk, HTTP cache relying on conditional
+// requests (ETag and Last-Modified) to revalidate entries.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ErrOfflineMiss marks a request which can't be served from the cache in offline mode.
+var ErrOfflineMiss = errors.New("not available in the cache (offline)")
+
+// maxRedirects the amount of redirects followed for a single request.
+const maxRedirects = 10
+
+// Cache is a http.RoundTripper caching successful GET responses on disk. Cached entries are
+// revalidated using conditional requests, and redirects are followed by the cache itself so
+// entries are stored under the URL originally requested.
+type Cache struct {
+	dir     string            // cache root directory
+	offline bool              // only serve from the cache, never reach the network
+	next    http.RoundTripper // transport used to reach the network
+
+	hits        atomic.Int64 // responses served from the cache
+	revalidated atomic.Int64 // hits confirmed by the server (304)
+	misses      atomic.Int64 // responses fetched from the network
+}
+
+var _ http.RoundTripper = &Cache{}
+
+// entry metadata of a cached response, the body is stored as a blob named after its digest.
+type entry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	Header       http.Header `json:"header"`
+	Digest       string      `json:"digest"`
+}
+
+// Stats summarizes the cache usage.
+type Stats struct {
+	Hits        int64
+	Revalidated int64
+	Misses      int64
+}
+
+// String renders the statistics in a human readable way.
+func (s Stats) String() string {
+	return fmt.Sprintf("%d hits (%d revalidated), %d misses", s.Hits, s.Revalidated, s.Misses)
+}
+
+// DefaultDir the default cache location, "catalog-cd" under the user cache directory
+// ($XDG_CACHE_HOME on Linux).
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "catalog-cd")
+}
+
+// Stats returns the cache usage so far.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Revalidated: c.revalidated.Load(),
+		Misses:      c.misses.Load(),
+	}
+}
+
+// WithTransport sets the transport used to reach the network, http.DefaultTransport by default.
+func (c *Cache) WithTransport(next http.RoundTripper) *Cache {
+	c.next = next
+	return c
+}
+
+// transport returns the next transport, resolving the default transport on demand.
+func (c *Cache) transport() http.RoundTripper {
+	if c.next != nil {
+		return c.next
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip serves GET requests from the cache when possible, other requests are sent as-is.
+func (c *Cache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.transport().RoundTrip(req)
+	}
+	key := c.key(req)
+	e, _ := c.load(key)
+
+	if c.offline {
+		if e == nil {
+			return nil, fmt.Errorf("%s: %w", req.URL, ErrOfflineMiss)
+		}
+		c.hits.Add(1)
+		return c.respond(req, e)
+	}
+
+	conditional := req.Clone(req.Context())
+	if e != nil {
+		if e.ETag != "" {
+			conditional.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.LastModified != "" {
+			conditional.Header.Set("If-Modified-Since", e.LastModified)
+		}
+	}
+	resp, err := c.follow(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && e != nil:
+		resp.Body.Close()
+		c.hits.Add(1)
+		c.revalidated.Add(1)
+		return c.respond(req, e)
+	case resp.StatusCode == http.StatusOK:
+		c.misses.Add(1)
+		defer resp.Body.Close()
+		e, err = c.store(key, req.URL.String(), resp)
+		if err != nil {
+			return nil, err
+		}
+		return c.respond(req, e)
+	default:
+		return resp, nil
+	}
+}
+
+// follow sends the request following redirects, the conditional headers are kept across
+// redirects while the credentials are dropped when the host changes.
+func (c *Cache) follow(req *http.Request) (*http.Response, error) {
+	for i := 0; ; i++ {
+		resp, err := c.transport().RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		location := resp.Header.Get("Location")
+		if location == "" || i >= maxRedirects {
+			return resp, nil
+		}
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+			http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		default:
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		u, err := req.URL.Parse(location)
+		if err != nil {
+			return nil, err
+		}
+		next := req.Clone(req.Context())
+		next.URL = u
+		next.Host = ""
+		if u.Host != req.URL.Host {
+			next.Header.Del("Authorization")
+			next.Header.Del("Private-Token")
+		}
+		req = next
+	}
+}
+
+// credentialHeaders headers carrying the request credentials.
+var credentialHeaders = []string{"Authorization", "Private-Token"}
+
+// key identifies the request on the cache, the "Accept" header is part of the key as the
+// same URL may serve different representations. The digest of the request credentials is part
+// of the key as well, so a response to an authenticated request is only served to requests
+// carrying the same credentials.
+func (c *Cache) key(req *http.Request) string {
+	data := req.URL.String() + "\n" + req.Header.Get("Accept")
+	credentials := sha256.New()
+	authenticated := false
+	for _, name := range credentialHeaders {
+		if v := req.Header.Get(name); v != "" {
+			fmt.Fprintf(credentials, "%s: %s\n", name, v)
+			authenticated = true
+		}
+	}
+	if authenticated {
+		data += "\n" + hex.EncodeToString(credentials.Sum(nil))
+	}
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, "entries", key+".json")
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.dir, "blobs", "sha256", digest)
+}
+
+// load reads the cache entry, making sure its blob is still present.
+func (c *Cache) load(key string) (*entry, error) {
+	payload, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(c.blobPath(e.Digest)); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// store writes the response body as a blob, and the respective entry.
+func (c *Cache) store(key, url string, resp *http.Response) (*entry, error) {
+	blobs := filepath.Dir(c.blobPath("-"))
+	if err := os.MkdirAll(blobs, 0o755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(blobs, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(tmp, io.TeeReader(resp.Body, h)); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to cache %s: %w", url, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	e := &entry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Header:       resp.Header.Clone(),
+		Digest:       hex.EncodeToString(h.Sum(nil)),
+	}
+	if err := os.Rename(tmp.Name(), c.blobPath(e.Digest)); err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return e, writeFileAtomic(c.entryPath(key), payload)
+}
+
+// respond builds the response out of the cache entry.
+func (c *Cache) respond(req *http.Request, e *entry) (*http.Response, error) {
+	payload, err := os.ReadFile(c.blobPath(e.Digest))
+	if err != nil {
+		return nil, err
+	}
+	header := e.Header.Clone()
+	header.Del("Content-Encoding")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(payload)),
+		ContentLength: int64(len(payload)),
+		Request:       req,
+	}, nil
+}
+
+// writeFileAtomic writes the file on a temporary location before moving it in place.
+func writeFileAtomic(file string, payload []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// New instantiates the cache on the informed directory, in offline mode the requests are only
+// served from the cache.
+func New(dir string, offline bool) (*Cache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cache directory is not set")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory %s: %w", dir, err)
+	}
+	return &Cache{dir: dir, offline: offline}, nil
+}
@@ -0,0 +1,112 @@
+package cache_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"gotest.tools/v3/assert"
+)
+
+// get requests the URL using the cache, returns the body.
+func get(t *testing.T, c *cache.Cache, url string) (string, error) {
+	t.Helper()
+	client := &http.Client{Transport: c}
+	resp, err := client.Get(url) // nolint:noctx
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	payload, err := io.ReadAll(resp.Body)
+	return string(payload), err
+}
+
+func TestCache(t *testing.T) {
+	var downloads, notModified atomic.Int64
+	mux := http.NewServeMux()
+	mux.HandleFunc("/releases/download/v0.1.0/catalog.yaml", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/objects/catalog.yaml", http.StatusFound)
+	})
+	mux.HandleFunc("/objects/catalog.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "version: v1")
+	})
+	server := httptest.NewServer(mux)
+	url := server.URL + "/releases/download/v0.1.0/catalog.yaml"
+
+	dir := t.TempDir()
+	c, err := cache.New(dir, false)
+	assert.NilError(t, err)
+
+	for i := 0; i < 3; i++ {
+		body, err := get(t, c, url)
+		assert.NilError(t, err)
+		assert.Equal(t, body, "version: v1")
+	}
+	assert.Equal(t, downloads.Load(), int64(1))
+	assert.Equal(t, notModified.Load(), int64(2))
+	assert.Equal(t, c.Stats(), cache.Stats{Hits: 2, Revalidated: 2, Misses: 1})
+
+	// offline, the server is not reachable anymore
+	server.Close()
+	offline, err := cache.New(dir, true)
+	assert.NilError(t, err)
+	body, err := get(t, offline, url)
+	assert.NilError(t, err)
+	assert.Equal(t, body, "version: v1")
+
+	_, err = get(t, offline, server.URL+"/releases/download/v0.2.0/catalog.yaml")
+	assert.Assert(t, errors.Is(err, cache.ErrOfflineMiss), "unexpected error: %v", err)
+}
+
+func TestCacheAuthenticated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, "private")
+	}))
+	url := server.URL + "/releases/assets/1"
+
+	dir := t.TempDir()
+	c, err := cache.New(dir, false)
+	assert.NilError(t, err)
+	req, err := http.NewRequest(http.MethodGet, url, nil) // nolint:noctx
+	assert.NilError(t, err)
+	req.Header.Set("Authorization", "token secret")
+	resp, err := c.RoundTrip(req)
+	assert.NilError(t, err)
+	payload, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, string(payload), "private")
+
+	// the private response is not served to unauthenticated requests, nor other credentials
+	server.Close()
+	offline, err := cache.New(dir, true)
+	assert.NilError(t, err)
+	_, err = get(t, offline, url)
+	assert.Assert(t, errors.Is(err, cache.ErrOfflineMiss), "unexpected error: %v", err)
+
+	req.Header.Set("Authorization", "token other")
+	_, err = offline.RoundTrip(req)
+	assert.Assert(t, errors.Is(err, cache.ErrOfflineMiss), "unexpected error: %v", err)
+
+	req.Header.Set("Authorization", "token secret")
+	resp, err = offline.RoundTrip(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+}
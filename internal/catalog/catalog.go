@@ -3,24 +3,55 @@ package catalog
 import (
 	"archive/tar"
 	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"io/fs"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
 )
 
+const (
+	// sourceAnnotation annotation holding the repository the resource comes from.
+	sourceAnnotation = "tekton.dev/source"
+	// channelAnnotation annotation holding the channel of the release the resource comes from.
+	channelAnnotation = "tekton.dev/channel"
+)
+
+// annotation is a resource annotation added during the catalog generation.
+type annotation struct {
+	key   string
+	value string
+}
+
+// existsIn asserts the annotation key is already set on the informed YAML lines.
+func (a annotation) existsIn(lines []string) bool {
+	pattern := regexp.MustCompile(fmt.Sprintf(`^\s+%s:\s*".*"$`, regexp.QuoteMeta(a.key)))
+	for _, line := range lines {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
 // Catalog represent the list of repositories from which we fetch informations.
 type Catalog struct {
 	Repositories map[string]Repository
@@ -35,69 +66,362 @@ type Repository map[string]Release
 type Release struct {
 	ResourcesURI string
 	Catalog      contract.Catalog
+	// Channel release channel (stable, prerelease or draft), empty means stable.
+	Channel string
+	// Tag release tag, the version is the tag without "v" prefix.
+	Tag string
+	// ContractURI contract location, and ContractSHA256 its digest.
+	ContractURI    string
+	ContractSHA256 string
+	// ResourcesSHA256 expected digest of the resources tarball, verified before extracting the
+	// resources when set (pinned by the lock file).
+	ResourcesSHA256 string
+	// PublicKey trusted public key pinned by the externals configuration (file location or
+	// inline PEM), when set the resources signatures are verified before writing them.
+	PublicKey string
+	// RequireSignatures the resources tarball must match the digest recorded on the contract,
+	// and its detached signature (ResourcesSignatureURI) made with the trusted public key.
+	RequireSignatures     bool
+	ResourcesSignatureURI string
 }
 
-func FetchFromExternals(e config.External, client *api.RESTClient) (Catalog, error) {
+// FetchFromExternals lists the releases of every repository and fetches their contracts,
+// using up to "Parallelism" concurrent requests. In strict mode every failure is reported,
+// instead of the first one. The options clients are resolving the credentials of each host,
+// when nil they are built out of the externals hosts configuration.
+func FetchFromExternals(ctx context.Context, e config.External, opts Options) (Catalog, error) {
+	clients := opts.Clients
+	if clients == nil {
+		clients = fetcher.NewClients(e.Hosts, nil)
+	}
 	c := Catalog{
 		Repositories: map[string]Repository{},
 	}
-	for _, r := range e.Repositories {
-		if r.Name == "" {
-			// Name is empty, take the last part of the URL
-			r.Name = filepath.Base(r.URL)
-		}
-		c.Repositories[r.Name] = Repository{}
+	repositories := make([]config.Repository, len(e.Repositories))
+	releases := make([][]fetcher.Release, len(e.Repositories))
+	for i, r := range e.Repositories {
+		r.Name = repositoryName(r)
+		repositories[i] = r
+	}
 
-		m, err := fetcher.FetchContractsFromRepository(r, client)
+	// listing the releases of each repository
+	err := opts.run()(ctx, opts.Parallelism, len(repositories), func(ctx context.Context, i int) error {
+		source, err := fetcher.NewSource(repositories[i], clients)
 		if err != nil {
-			return c, err
+			return err
 		}
-		for _, v := range r.IgnoreVersions {
-			// Remove ignored versions from map
-			delete(m, v)
+		ctx, cancel := opts.withTimeout(ctx)
+		defer cancel()
+		releases[i], err = fetcher.ListReleases(ctx, repositories[i], source)
+		return err
+	})
+	if err != nil {
+		return c, err
+	}
+
+	// fetching the contract of each release, of all repositories
+	type job struct{ repository, release int }
+	jobs := []job{}
+	for i := range releases {
+		for j := range releases[i] {
+			jobs = append(jobs, job{repository: i, release: j})
 		}
+	}
+	err = opts.run()(ctx, opts.Parallelism, len(jobs), func(ctx context.Context, i int) error {
+		ctx, cancel := opts.withTimeout(ctx)
+		defer cancel()
+		return clients.FetchReleaseContract(ctx, &releases[jobs[i].repository][jobs[i].release])
+	})
+	if err != nil {
+		return c, err
+	}
 
-		for version, contract := range m {
-			resourcesDownloaldURI := fmt.Sprintf("%s/releases/download/%s/%s", r.URL, version, r.ResourcesTarballName)
-			version = strings.TrimPrefix(version, "v")
+	for i, r := range repositories {
+		c.Repositories[r.Name] = Repository{}
+		for _, release := range releases[i] {
+			version := strings.TrimPrefix(release.Version, "v")
 			c.Repositories[r.Name][version] = Release{
-				ResourcesURI: resourcesDownloaldURI,
-				Catalog:      contract.Catalog,
+				ResourcesURI:   release.ResourcesURL,
+				Catalog:        release.Contract.Catalog,
+				Channel:        release.Channel,
+				Tag:            release.Version,
+				ContractURI:    release.ContractURL,
+				ContractSHA256: release.ContractSHA,
+				PublicKey:      r.PublicKey,
+
+				RequireSignatures:     release.TrustedKey != "",
+				ResourcesSignatureURI: release.ResourcesSignatureURL,
 			}
 		}
 	}
 	return c, nil
 }
 
-func GenerateFilesystem(path string, c Catalog, resourceType string) error {
-	for name, repository := range c.Repositories {
-		fmt.Fprintf(os.Stderr, "# Fetching resources from %s\n", name)
-		for version, release := range repository {
-			fmt.Fprintf(os.Stderr, "## Fetching version %s\n", version)
-			if err := fetchAndExtract(path, release, version, resourceType); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to fetch resource %s: %v, skipping\n", release.ResourcesURI, err)
-				continue
+// repositoryName returns the repository name, the last part of the URL when not set.
+func repositoryName(r config.Repository) string {
+	if r.Name == "" {
+		return filepath.Base(r.URL)
+	}
+	return r.Name
+}
+
+// GenerateFilesystem fetches and extracts the resources of every release on the informed path,
+// using up to "Parallelism" concurrent downloads. The progress is reported in a deterministic
+// order, sorted by repository and version.
+//
+// The generation is incremental, versions already present on the path whose resources match
+// the contract checksums are left untouched without downloading them again, unless they were
+// written with another verification policy (pinned public key, required signatures). The version
+// folders generated are recorded on the target manifest with their policy, see Prune. The versions which couldn't be
+// fetched are skipped, unless in strict mode where all the failures are returned.
+func GenerateFilesystem(ctx context.Context, path string, c Catalog, resourceType string, opts Options) (Report, error) {
+	type job struct {
+		name    string
+		version string
+		release Release
+		state   versionState  // version state before the generation
+		err     error         // the version couldn't be fetched
+		out     bytes.Buffer  // job output, printed once the job is done
+		done    chan struct{} // closed when the job is done
+	}
+	m, err := loadManifest(path)
+	if err != nil {
+		return Report{}, err
+	}
+	policies := m.policies()
+	jobs := []*job{}
+	for _, name := range sortedKeys(c.Repositories) {
+		for _, version := range sortedKeys(c.Repositories[name]) {
+			jobs = append(jobs, &job{
+				name:    name,
+				version: version,
+				release: c.Repositories[name][version],
+				done:    make(chan struct{}),
+			})
+		}
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- forEach(ctx, opts.Parallelism, len(jobs), func(ctx context.Context, i int) error {
+			j := jobs[i]
+			defer close(j.done)
+			state, err := inspectVersion(path, j.release, j.version, resourceType, policies)
+			if err != nil {
+				return err
 			}
+			j.state = state
+			if state == versionUpToDate {
+				fmt.Fprintf(&j.out, "## Version %s is up to date, skipping\n", j.version)
+				return nil
+			}
+			reqCtx, cancel := opts.withTimeout(ctx)
+			defer cancel()
+			fmt.Fprintf(&j.out, "## Fetching version %s\n", j.version)
+			if err := fetchAndExtract(reqCtx, opts.Clients, &j.out, path, j.release, j.version, resourceType, opts.archiveLimits()); err != nil {
+				if ctx.Err() != nil {
+					// the generation is canceled, as opposed to this request timing out
+					return ctx.Err()
+				}
+				if errors.Is(err, ErrLockMismatch) {
+					return err
+				}
+				j.err = err
+				fmt.Fprintf(&j.out, "Failed to fetch resource %s: %v, skipping\n", j.release.ResourcesURI, err)
+			}
+			return nil
+		})
+	}()
+
+	report := Report{}
+	failures := []error{}
+	failed := map[string]bool{}
+	finished := false // all the jobs are done, successfully
+	for i, j := range jobs {
+		if i == 0 || jobs[i-1].name != j.name {
+			fmt.Fprintf(os.Stderr, "# Fetching resources from %s\n", j.name)
+		}
+		if !finished {
+			select {
+			case <-j.done:
+			case err := <-errC:
+				if err != nil {
+					return report, err
+				}
+				finished = true
+			}
+		}
+		<-j.done
+		fmt.Fprint(os.Stderr, j.out.String())
+		id := fmt.Sprintf("%s@%s", j.name, j.version)
+		switch {
+		case j.err != nil:
+			report.Failed = append(report.Failed, id)
+			failed[id] = true
+			failures = append(failures, fmt.Errorf("%s: %w", id, j.err))
+		case j.state == versionUpToDate:
+			report.Untouched = append(report.Untouched, id)
+		case j.state == versionChanged:
+			report.Updated = append(report.Updated, id)
+		default:
+			report.Added = append(report.Added, id)
 		}
 	}
-	return nil
+	if !finished {
+		if err := <-errC; err != nil {
+			return report, err
+		}
+	}
+	if opts.Strict && len(failures) > 0 {
+		return report, fmt.Errorf("failed to generate %d versions:\n%w", len(failures), errors.Join(failures...))
+	}
+	return report, recordManifest(path, c, resourceType, failed)
 }
 
-func fetchAndExtract(path string, release Release, version, resourceType string) error {
-	resp, err := http.Get(release.ResourcesURI) // nolint:gosec,noctx
+// sortedKeys returns the map keys sorted.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fetchAndExtract(ctx context.Context, clients *fetcher.Clients, out io.Writer, path string, release Release, version, resourceType string, limits archiveLimits) error {
+	rc, err := clients.Open(ctx, release.ResourcesURI)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("status error: %v", resp.StatusCode)
+	defer rc.Close()
+	var r io.Reader = rc
+	if release.ResourcesSHA256 != "" || release.RequireSignatures {
+		// the tarball is verified before extracting any resource, it's read in memory up to the
+		// extraction limit
+		payload, err := io.ReadAll(io.LimitReader(rc, limits.bytes+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(payload)) > limits.bytes {
+			return fmt.Errorf("%w: tarball larger than %d bytes", ErrUnsafeArchive, limits.bytes)
+		}
+		digest := sha256.Sum256(payload)
+		actual := hex.EncodeToString(digest[:])
+		if release.ResourcesSHA256 != "" && actual != release.ResourcesSHA256 {
+			return fmt.Errorf("%w: resources %s sha256 is %s, locked %s",
+				ErrLockMismatch, release.ResourcesURI, actual, release.ResourcesSHA256)
+		}
+		if release.RequireSignatures {
+			if err := verifyTarball(ctx, clients, release, actual, payload); err != nil {
+				return err
+			}
+		}
+		r = bytes.NewReader(payload)
 	}
 	// Let's get the file we want to fetch from the release object
 	tektonResources := getResourcesFromType(release, resourceType)
-	return untar(path, version, tektonResources, release.ResourcesURI, resp.Body) // Pass release.ResourcesURI to untar
+
+	// the resources are extracted on a temporary folder, and moved in place once all of them
+	// are verified, so a broken tarball doesn't leave partially written files
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(path, ".extract-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	// the signatures are extracted aside, they are not part of the catalog
+	signatures := ""
+	if release.PublicKey != "" {
+		if signatures, err = os.MkdirTemp("", "catalog-cd-signatures-*"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(signatures)
+	}
+	if err := untar(out, tmp, signatures, version, tektonResources, r, limits); err != nil {
+		return err
+	}
+	if release.PublicKey != "" {
+		var annotations map[string]string
+		if release.Catalog.Attestation != nil {
+			annotations = release.Catalog.Attestation.Annotations
+		}
+		if err := verifySignatures(ctx, out, tmp, signatures, release.PublicKey, version, tektonResources, annotations); err != nil {
+			return err
+		}
+	}
+	// Add "source" (and "channel") annotations to task YAML file, once verified
+	if err := annotateResources(tmp, version, tektonResources, releaseAnnotations(release)); err != nil {
+		return err
+	}
+	return moveFiles(tmp, path)
+}
+
+// moveFiles moves the files of the src tree into dst, replacing the existing ones.
+func moveFiles(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			return err
+		}
+		return os.Rename(path, target)
+	})
 }
 
-func untar(dst, version string, tektonResources map[string]contract.TektonResource, resourcesURI string, r io.Reader) error {
+// releaseAnnotations annotations added to the resources of the release, the source repository
+// and the channel for releases which aren't stable.
+func releaseAnnotations(release Release) []annotation {
+	annotations := []annotation{{
+		key:   sourceAnnotation,
+		value: extractRepositoryURL(release.ResourcesURI),
+	}}
+	if release.Channel != "" && release.Channel != config.ChannelStable {
+		annotations = append(annotations, annotation{key: channelAnnotation, value: release.Channel})
+	}
+	return annotations
+}
+
+// ErrUnsafeArchive marks a resources tarball rejected as it could write outside of the catalog
+// target (absolute paths, ".." segments, links) or exceeds the extraction limits.
+var ErrUnsafeArchive = errors.New("unsafe resources tarball")
+
+// archiveLimits caps the resources tarball extraction.
+type archiveLimits struct {
+	entries int   // maximum amount of entries, whatever their type
+	bytes   int64 // maximum amount of bytes of the regular files
+}
+
+// archiveEntryName cleans the tarball entry name ("./tasks/foo/foo.yaml" is "tasks/foo/foo.yaml"),
+// rejecting names escaping the extraction folder.
+func archiveEntryName(name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(name) || !filepath.IsLocal(filepath.FromSlash(clean)) {
+		return "", fmt.Errorf("%w: entry %q escapes the target folder", ErrUnsafeArchive, name)
+	}
+	return clean, nil
+}
+
+// untar extracts the contract resources (and README files) of the tarball in the version folders
+// of dst, verifying their checksum. When sigDir is informed the signature files referenced by
+// the contract are extracted there.
+func untar(out io.Writer, dst, sigDir, version string, tektonResources map[string]contract.TektonResource, r io.Reader, limits archiveLimits) error {
+	signatureFiles := map[string]bool{}
+	if sigDir != "" {
+		for _, tr := range tektonResources {
+			if tr.Signature != "" {
+				signatureFiles[path.Clean(tr.Signature)] = true
+			}
+		}
+	}
+
 	gzr, err := gzip.NewReader(r)
 	if err != nil {
 		return err
@@ -106,12 +430,14 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 
 	tr := tar.NewReader(gzr)
 
+	errs := []error{}
+	entries, size := 0, int64(0)
 	for {
 		header, err := tr.Next()
 		switch {
 		// if no more files are found return
 		case errors.Is(err, io.EOF):
-			return nil
+			return errors.Join(errs...)
 		// return any other error
 		case err != nil:
 			return err
@@ -120,15 +446,52 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 			continue
 		}
 
+		// every entry counts, the ignored ones are decompressed as well
+		entries++
+		if entries > limits.entries {
+			return fmt.Errorf("%w: more than %d entries", ErrUnsafeArchive, limits.entries)
+		}
+		if header.Typeflag == tar.TypeReg {
+			size += header.Size
+			if header.Size < 0 || size > limits.bytes {
+				return fmt.Errorf("%w: more than %d bytes once extracted", ErrUnsafeArchive, limits.bytes)
+			}
+		}
+		name, err := archiveEntryName(header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("%w: entry %q is a link to %q, links are not supported",
+				ErrUnsafeArchive, header.Name, header.Linkname)
+		default:
+			fmt.Fprintf(out, "### Ignoring %s (unsupported entry type %q)\n", header.Name, header.Typeflag)
+			continue
+		}
+
 		// the target location where the dir/file should be created
-		filename := filepath.Base(header.Name)
-		versionnedFolder := filepath.Join(filepath.Dir(header.Name), version)
+		filename := path.Base(name)
+		versionnedFolder := filepath.Join(filepath.FromSlash(path.Dir(name)), version)
+		if !filepath.IsLocal(versionnedFolder) {
+			return fmt.Errorf("%w: version %q escapes the target folder", ErrUnsafeArchive, version)
+		}
 		targetFolder := filepath.Join(dst, versionnedFolder)
 		target := filepath.Join(targetFolder, filename)
 
-		tektonResource, ok := tektonResources[header.Name]
+		tektonResource, ok := tektonResources[name]
+		if !ok {
+			tektonResource, ok = tektonResources[header.Name]
+		}
+		if !ok && header.Typeflag == tar.TypeReg && signatureFiles[name] {
+			if err := extractFile(tr, filepath.Join(sigDir, filepath.FromSlash(name))); err != nil {
+				return err
+			}
+			continue
+		}
 		if !ok && filename != "README.md" {
-			fmt.Fprintf(os.Stderr, "### Ignoring %s (file not present in the catalog file)\n", header.Name)
+			fmt.Fprintf(out, "### Ignoring %s (file not present in the catalog file)\n", header.Name)
 			continue
 		}
 
@@ -151,7 +514,7 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 			}
 		// if it's a file create it
 		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR|os.O_TRUNC, os.FileMode(header.Mode))
 			if err != nil {
 				return err
 			}
@@ -169,27 +532,56 @@ func untar(dst, version string, tektonResources map[string]contract.TektonResour
 
 			if filename != "README.md" {
 				if tektonResource.Checksum != sum {
-					fmt.Fprintf(os.Stderr, "%s checksum is different than the specified checksum in the catalog file: %s", sum, tektonResource.Checksum)
-					// FIXME: maybe handle *all* file before erroring out ?
-					return fmt.Errorf("invalid checksum for %s: %s != %s", filename, sum, tektonResource.Checksum)
-				}
-				fmt.Fprintf(os.Stderr, "✅ %s\n", tektonResource.Filename)
-			}
-
-			// Add "source" annotation to task YAML file
-			if strings.HasSuffix(target, ".yaml") {
-				if err := addSourceAnnotationToTask(target, resourcesURI); err != nil {
-					return err
+					// all the files are verified before erroring out
+					fmt.Fprintf(out, "❌ %s checksum %s is different than the specified checksum in the catalog file: %s\n",
+						tektonResource.Filename, sum, tektonResource.Checksum)
+					errs = append(errs, fmt.Errorf("invalid checksum for %s: %s != %s", filename, sum, tektonResource.Checksum))
+					continue
 				}
+				fmt.Fprintf(out, "✅ %s\n", tektonResource.Filename)
 			}
 		}
 	}
 }
 
-func addSourceAnnotationToTask(file, resourcesURI string) error {
-	// Add the new annotation
-	repoURL := extractRepositoryURL(resourcesURI)
+// extractFile writes the current tarball entry on the informed location.
+func extractFile(r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil { // nolint:gosec
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// annotateResources adds the annotations to the extracted YAML resources.
+func annotateResources(dst, version string, tektonResources map[string]contract.TektonResource, annotations []annotation) error {
+	for _, name := range sortedKeys(tektonResources) {
+		target, ok := localResourceTarget(dst, name, version)
+		if !ok || !strings.HasSuffix(target, ".yaml") {
+			continue
+		}
+		if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		// annotating invalidates the Tekton trusted resources signature, embedded on the resource
+		if signed, err := trustedresources.Signed(target); err == nil && signed {
+			continue
+		}
+		if err := addAnnotationsToTask(target, annotations); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+func addAnnotationsToTask(file string, annotations []annotation) error {
 	// Open the Task YAML file
 	f, err := os.OpenFile(file, os.O_RDWR, 0o644)
 	if err != nil {
@@ -199,42 +591,38 @@ func addSourceAnnotationToTask(file, resourcesURI string) error {
 
 	// Create a scanner to read the file line by line
 	scanner := bufio.NewScanner(f)
-	var updatedContent []string
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	// Check for scanner errors
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	// only the first annotations block is annotated, a single resource is expected
+	if err := resource.SingleDocument([]byte(strings.Join(lines, "\n"))); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(file), err)
+	}
 
 	// Regular expression pattern to match the annotations in Task metadata
 	annotationsPattern := regexp.MustCompile(`^\s+annotations:\s*$`)
-	sourceAnnotationPattern := regexp.MustCompile(`^\s+tekton\.dev/source:\s*".*"$`)
-
-	// Flag to indicate if the "tekton.dev/source" annotation is already present
-	var sourceAnnotationExists bool
-
-	// Read the file line by line
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Check if the line matches the annotations pattern
-		if annotationsPattern.MatchString(line) {
-			// If annotations block is found, initialize sourceAnnotationExists to false
-			sourceAnnotationExists = false
-		} else if !sourceAnnotationExists && sourceAnnotationPattern.MatchString(line) {
-			// If source annotation is found within annotations block, set sourceAnnotationExists to true
-			sourceAnnotationExists = true
-		}
 
+	var updatedContent []string
+	annotated := false
+	for _, line := range lines {
 		// Append the line to updatedContent slice
 		updatedContent = append(updatedContent, line)
-
-		// Check if we are still within the annotations block
-		if !sourceAnnotationExists && annotationsPattern.MatchString(line) {
-			// Add the source annotation as the first line of the annotations block
-			updatedContent = append(updatedContent, fmt.Sprintf("    tekton.dev/source: \"%s\"", repoURL))
-			sourceAnnotationExists = true // Set sourceAnnotationExists to true after adding the annotation
+		if annotated || !annotationsPattern.MatchString(line) {
+			continue
 		}
-	}
-
-	// Check for scanner errors
-	if err := scanner.Err(); err != nil {
-		return err
+		// Add the annotations as the first lines of the (first) annotations block, unless
+		// they are already present
+		for _, a := range annotations {
+			if !a.existsIn(lines) {
+				updatedContent = append(updatedContent, fmt.Sprintf("    %s: \"%s\"", a.key, a.value))
+			}
+		}
+		annotated = true
 	}
 
 	// Clear the file content and write the updated content
@@ -251,34 +639,45 @@ func addSourceAnnotationToTask(file, resourcesURI string) error {
 	return writer.Flush()
 }
 
-// Function to extract repository URL from resource tarball URL.
-func extractRepositoryURL(url string) string {
-	// Assuming the resource tarball URL format is consistent: https://github.com/{organization}/{repository}/releases/download/{version}/resources.tar.gz
-	parts := strings.Split(url, "/")
-	if len(parts) < 5 {
+// extractRepositoryURL returns the repository URL out of the resources tarball location, on any
+// host and whatever the repository path depth (GitLab subgroups, …):
+//   - {scheme}://{host}/{repository}/releases/download/{version}/{file} (GitHub, Gitea)
+//   - {scheme}://{host}/{project}/-/releases/{version}/downloads/{file} (GitLab)
+//   - {scheme}://{api host}/[api/v3/]repos/{repository}/releases/assets/{id} (GitHub API)
+//   - oci://{registry}/{repository}@{digest}
+//
+// Other locations are reduced to their first two path segments.
+func extractRepositoryURL(uri string) string {
+	if strings.HasPrefix(uri, oci.Scheme) {
+		return strings.Split(uri, "@")[0]
+	}
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" {
 		return ""
 	}
-	return strings.Join(parts[:5], "/")
+	host, path := u.Host, u.Path
+	switch {
+	case strings.Contains(path, "/-/"):
+		path = path[:strings.Index(path, "/-/")]
+	case strings.Contains(path, "/releases/assets/") && strings.Contains(path, "/repos/"):
+		path = path[strings.Index(path, "/repos/")+len("/repos") : strings.Index(path, "/releases/assets/")]
+		host = strings.TrimPrefix(host, "api.")
+	case strings.Contains(path, "/releases/download/"):
+		path = path[:strings.Index(path, "/releases/download/")]
+	default:
+		segments := strings.SplitN(strings.Trim(path, "/"), "/", 3)
+		if len(segments) < 2 {
+			return ""
+		}
+		path = "/" + strings.Join(segments[:2], "/")
+	}
+	return fmt.Sprintf("%s://%s%s", u.Scheme, host, path)
 }
 
 func getResourcesFromType(release Release, resourceType string) map[string]contract.TektonResource {
 	m := map[string]contract.TektonResource{}
-	switch resourceType {
-	case "tasks":
-		for _, r := range release.Catalog.Resources.Tasks {
-			m[r.Filename] = *r
-		}
-	case "pipelines":
-		for _, r := range release.Catalog.Resources.Pipelines {
-			m[r.Filename] = *r
-		}
-	case "":
-		for _, r := range release.Catalog.Resources.Tasks {
-			m[r.Filename] = *r
-		}
-		for _, r := range release.Catalog.Resources.Pipelines {
-			m[r.Filename] = *r
-		}
+	for _, r := range release.Catalog.Resources.OfType(resourceType) {
+		m[r.Filename] = *r
 	}
 	return m
 }
@@ -1,14 +1,28 @@
 package catalog_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/google/go-containerregistry/pkg/registry"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 	"gopkg.in/h2non/gock.v1"
 	"gotest.tools/v3/assert"
 	"gotest.tools/v3/fs"
@@ -24,6 +38,10 @@ func TestFetchFromExternal(t *testing.T) {
 	}
 	r := strings.TrimPrefix(repo.URL, "https://github.com/")
 
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s$", r)).
+		Reply(200).
+		BodyString(`{"private": false}`)
 	gock.New("https://api.github.com").
 		Get(fmt.Sprintf("repos/%s/releases", r)).
 		Reply(200).
@@ -48,7 +66,7 @@ func TestFetchFromExternal(t *testing.T) {
 			ResourcesTarballName: "resources.tar.gz",
 		}},
 	}
-	c, err := catalog.FetchFromExternals(e, client)
+	c, err := catalog.FetchFromExternals(context.Background(), e, catalog.Options{Clients: fetcher.NewClients(nil, nil).WithGitHubClient("github.com", client)})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -93,11 +111,11 @@ func TestGenerateFilesystem(t *testing.T) {
 			},
 		},
 	}
-	err := catalog.GenerateFilesystem(dir.Path(), c, "")
+	_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "", catalog.Options{})
 	if err != nil {
 		t.Fatal(err)
 	}
-	expected := fs.Expected(t, fs.WithDir("tasks",
+	expected := fs.Expected(t, fs.WithFile(catalog.ManifestFilename, "", fs.MatchAnyFileContent), fs.WithDir("tasks",
 		fs.WithDir("go-crane-image",
 			fs.WithDir("0.5.0",
 				fs.WithFile("go-crane-image.yaml", "", fs.WithBytes(golden.Get(t, "tasks/go-crane-image/go-crane-image.yaml"))),
@@ -114,3 +132,428 @@ func TestGenerateFilesystem(t *testing.T) {
 
 	assert.Assert(t, fs.Equal(dir.Path(), expected))
 }
+
+func TestGenerateFilesystemFromOCI(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	repository := fmt.Sprintf("oci://%s/shortbrain/golang-tasks", strings.TrimPrefix(server.URL, "http://"))
+
+	if _, err := oci.Push(context.Background(), repository+":v0.5.0", "testdata/catalog.simple.yaml", "testdata/resources.tar.gz"); err != nil {
+		t.Fatal(err)
+	}
+
+	e := config.External{
+		Repositories: []config.Repository{{
+			Name:                 "sbr-golang",
+			URL:                  repository,
+			CatalogName:          "catalog.simple.yaml",
+			ResourcesTarballName: "resources.tar.gz",
+		}},
+	}
+	c, err := catalog.FetchFromExternals(context.Background(), e, catalog.Options{Parallelism: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := c.Repositories["sbr-golang"]["0.5.0"]
+	if !ok {
+		t.Fatalf("Should have fetched version 0.5.0, got %v", c.Repositories)
+	}
+	assert.Equal(t, len(release.Catalog.Resources.Tasks), 2)
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+	if _, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Parallelism: 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, task := range []string{"go-crane-image", "go-ko-image"} {
+		payload, err := os.ReadFile(filepath.Join(dir.Path(), "tasks", task, "0.5.0", task+".yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Assert(t, strings.Contains(string(payload), fmt.Sprintf("tekton.dev/source: %q", repository)))
+		_, err = os.Stat(filepath.Join(dir.Path(), "tasks", task, "0.5.0", "README.md"))
+		assert.NilError(t, err)
+	}
+}
+
+func TestGenerateFilesystemChannelAnnotation(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	gock.New("https://fake.host").
+		Get("resources.tar.gz").
+		Reply(200).
+		File("testdata/resources.tar.gz")
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	c := catalog.Catalog{
+		Repositories: map[string]catalog.Repository{
+			"sbr-golang": map[string]catalog.Release{
+				"0.5.0-rc.1": {
+					ResourcesURI: "https://fake.host/repo/resources.tar.gz",
+					Channel:      config.ChannelPreRelease,
+					Catalog: contract.Catalog{
+						Resources: &contract.Resources{
+							Tasks: []*contract.TektonResource{{
+								Name:     "go-crane-image",
+								Version:  "0.5.0-rc.1",
+								Filename: "tasks/go-crane-image/go-crane-image.yaml",
+								Checksum: "9b1f8e2ecbb5795727de93a6b95bbed2a4f44871f0f0ded6a2d8a04b2283a2b9",
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	if _, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Parallelism: 2}); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := os.ReadFile(filepath.Join(dir.Path(), "tasks", "go-crane-image", "0.5.0-rc.1", "go-crane-image.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Assert(t, strings.Contains(string(payload), `tekton.dev/channel: "prerelease"`))
+	assert.Equal(t, strings.Count(string(payload), "tekton.dev/source:"), 1)
+}
+
+func TestGenerateFilesystemCanceled(t *testing.T) {
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	c := catalog.Catalog{
+		Repositories: map[string]catalog.Repository{
+			"sbr-golang": map[string]catalog.Release{
+				"0.5.0": {ResourcesURI: "https://fake.host/repo/resources.tar.gz"},
+				"0.4.0": {ResourcesURI: "https://fake.host/repo/resources.tar.gz"},
+			},
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := catalog.GenerateFilesystem(ctx, dir.Path(), c, "", catalog.Options{Parallelism: 2})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGenerateFilesystemIncremental(t *testing.T) {
+	t.Cleanup(gock.Off)
+	mockResources := func() {
+		gock.New("https://fake.host").
+			Get("repo/resources.tar.gz").
+			Reply(200).
+			File("testdata/resources.tar.gz")
+	}
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+	_, c := lockedCatalog()
+
+	mockResources()
+	report, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Added, []string{"sbr-golang@0.5.0"})
+	assert.Assert(t, gock.IsDone())
+
+	// nothing changed, the resources are not downloaded again
+	report, err = catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Untouched, []string{"sbr-golang@0.5.0"})
+	assert.Equal(t, len(report.Added)+len(report.Updated)+len(report.Failed), 0)
+
+	// the resource was modified, it's extracted again
+	task := filepath.Join(dir.Path(), "tasks", "go-crane-image", "0.5.0", "go-crane-image.yaml")
+	assert.NilError(t, os.WriteFile(task, []byte("modified"), 0o644))
+	mockResources()
+	report, err = catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Updated, []string{"sbr-golang@0.5.0"})
+	payload, err := os.ReadFile(task)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(payload), "tekton.dev/source:"))
+
+	// the verification policy changed, the version is stale until verified with it
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.PublicKey = "testdata/missing.pub"
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+	for i := 0; i < 2; i++ {
+		mockResources()
+		report, err = catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+		assert.NilError(t, err)
+		assert.DeepEqual(t, report.Failed, []string{"sbr-golang@0.5.0"})
+		assert.Equal(t, len(report.Untouched), 0)
+	}
+}
+
+func TestGenerateFilesystemStrict(t *testing.T) {
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		File("testdata/resources.tar.gz")
+	gock.New("https://fake.host").
+		Get("repo/missing.tar.gz").
+		Reply(404)
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	// one resource with the wrong checksum, none of the version resources are written
+	invalid := c.Repositories["sbr-golang"]["0.5.0"]
+	invalid.Catalog.Resources = &contract.Resources{Tasks: []*contract.TektonResource{
+		invalid.Catalog.Resources.Tasks[0],
+		{
+			Name:     "go-ko-image",
+			Filename: "tasks/go-ko-image/go-ko-image.yaml",
+			Checksum: "0000000000000000000000000000000000000000000000000000000000000000",
+		},
+	}}
+	c.Repositories["sbr-golang"]["0.5.0"] = invalid
+	missing := c.Repositories["sbr-golang"]["0.5.0"]
+	missing.ResourcesURI = "https://fake.host/repo/missing.tar.gz"
+	c.Repositories["sbr-golang"]["0.4.0"] = missing
+
+	report, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Strict: true})
+	assert.ErrorContains(t, err, "failed to generate 2 versions")
+	assert.ErrorContains(t, err, "sbr-golang@0.4.0: status error: 404")
+	assert.ErrorContains(t, err, "sbr-golang@0.5.0: invalid checksum for go-ko-image.yaml")
+	assert.DeepEqual(t, report.Failed, []string{"sbr-golang@0.4.0", "sbr-golang@0.5.0"})
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t)))
+}
+
+// archiveEntry entry of a crafted resources tarball.
+type archiveEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+// craftArchive builds a gzipped tarball with the informed entries, as-is.
+func craftArchive(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644}
+		if e.typeflag == tar.TypeReg {
+			header.Size = int64(len(e.content))
+		}
+		assert.NilError(t, tw.WriteHeader(header))
+		_, err := tw.Write([]byte(e.content))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, tw.Close())
+	assert.NilError(t, gzw.Close())
+	return buf.Bytes()
+}
+
+func TestGenerateFilesystemUnsafeArchive(t *testing.T) {
+	const task = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+	sum := sha256.Sum256([]byte(task))
+	taskEntry := archiveEntry{name: "tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: task}
+
+	tests := []struct {
+		name     string
+		filename string
+		entries  []archiveEntry
+		options  catalog.Options
+		pinned   bool // the tarball digest is pinned, it's verified before extracting it
+		err      string
+	}{{
+		name:     "parent segments",
+		filename: "../../foo/foo.yaml",
+		entries:  []archiveEntry{{name: "../../foo/foo.yaml", typeflag: tar.TypeReg, content: task}},
+		err:      `entry "../../foo/foo.yaml" escapes the target folder`,
+	}, {
+		name:     "parent segments after cleaning",
+		filename: "tasks/../../foo.yaml",
+		entries:  []archiveEntry{{name: "tasks/../../foo.yaml", typeflag: tar.TypeReg, content: task}},
+		err:      `entry "tasks/../../foo.yaml" escapes the target folder`,
+	}, {
+		name:     "absolute path",
+		filename: "/tmp/foo/foo.yaml",
+		entries:  []archiveEntry{{name: "/tmp/foo/foo.yaml", typeflag: tar.TypeReg, content: task}},
+		err:      `entry "/tmp/foo/foo.yaml" escapes the target folder`,
+	}, {
+		name:     "symbolic link",
+		filename: "tasks/foo/foo.yaml",
+		entries:  []archiveEntry{{name: "tasks/foo/foo.yaml", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		err:      `entry "tasks/foo/foo.yaml" is a link to "/etc/passwd", links are not supported`,
+	}, {
+		name:     "hard link",
+		filename: "tasks/foo/foo.yaml",
+		entries: []archiveEntry{
+			{name: "tasks/bar/bar.yaml", typeflag: tar.TypeReg, content: task},
+			{name: "tasks/foo/foo.yaml", typeflag: tar.TypeLink, linkname: "tasks/bar/bar.yaml"},
+		},
+		err: `entry "tasks/foo/foo.yaml" is a link to "tasks/bar/bar.yaml", links are not supported`,
+	}, {
+		name:     "too many entries",
+		filename: "tasks/foo/foo.yaml",
+		entries:  []archiveEntry{{name: "tasks/", typeflag: tar.TypeDir}, {name: "tasks/foo/", typeflag: tar.TypeDir}, taskEntry},
+		options:  catalog.Options{MaxArchiveEntries: 2},
+		err:      "more than 2 entries",
+	}, {
+		name:     "too many bytes",
+		filename: "tasks/foo/foo.yaml",
+		entries:  []archiveEntry{taskEntry},
+		options:  catalog.Options{MaxArchiveBytes: 10},
+		err:      "more than 10 bytes once extracted",
+	}, {
+		name:     "too many bytes downloaded",
+		filename: "tasks/foo/foo.yaml",
+		entries:  []archiveEntry{taskEntry},
+		options:  catalog.Options{MaxArchiveBytes: 10},
+		pinned:   true,
+		err:      "tarball larger than 10 bytes",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			archive := craftArchive(t, tt.entries...)
+			gock.New("https://fake.host").
+				Get("repo/resources.tar.gz").
+				Reply(200).
+				Body(bytes.NewReader(archive))
+
+			parent := fs.NewDir(t, "parent", fs.WithDir("catalog"))
+			defer parent.Remove()
+
+			_, c := lockedCatalog()
+			release := c.Repositories["sbr-golang"]["0.5.0"]
+			release.Catalog.Resources = &contract.Resources{Tasks: []*contract.TektonResource{{
+				Name:     "foo",
+				Filename: tt.filename,
+				Checksum: hex.EncodeToString(sum[:]),
+			}}}
+			if tt.pinned {
+				digest := sha256.Sum256(archive)
+				release.ResourcesSHA256 = hex.EncodeToString(digest[:])
+			}
+			c.Repositories["sbr-golang"]["0.5.0"] = release
+
+			tt.options.Strict = true
+			_, err := catalog.GenerateFilesystem(context.Background(), parent.Join("catalog"), c, "tasks", tt.options)
+			assert.ErrorIs(t, err, catalog.ErrUnsafeArchive)
+			assert.ErrorContains(t, err, tt.err)
+			// nothing is written, neither in the target nor outside of it
+			assert.Assert(t, fs.Equal(parent.Path(), fs.Expected(t, fs.WithDir("catalog"))))
+		})
+	}
+}
+
+func TestGenerateFilesystemArchiveIgnoredEntries(t *testing.T) {
+	const task = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+	sum := sha256.Sum256([]byte(task))
+
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		Body(bytes.NewReader(craftArchive(t,
+			archiveEntry{name: "./tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: task},
+			archiveEntry{name: "tasks/foo/fifo", typeflag: tar.TypeFifo},
+			archiveEntry{name: "tasks/foo/other.yaml", typeflag: tar.TypeReg, content: "ignored"},
+		)))
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.Catalog.Resources = &contract.Resources{Tasks: []*contract.TektonResource{{
+		Name:     "foo",
+		Filename: "tasks/foo/foo.yaml",
+		Checksum: hex.EncodeToString(sum[:]),
+	}}}
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+
+	report, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Strict: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Added, []string{"sbr-golang@0.5.0"})
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+		fs.WithFile(catalog.ManifestFilename, "", fs.MatchAnyFileContent),
+		fs.WithDir("tasks", fs.WithDir("foo", fs.WithDir("0.5.0",
+			fs.WithFile("foo.yaml", "", fs.MatchAnyFileContent)))),
+	)))
+}
+
+func TestGenerateFilesystemMultipleDocuments(t *testing.T) {
+	const task = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n  annotations:\n    team: tekton\n" +
+		"---\napiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: bar\n  annotations:\n    team: tekton\n"
+	sum := sha256.Sum256([]byte(task))
+
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		Body(bytes.NewReader(craftArchive(t,
+			archiveEntry{name: "tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: task},
+		)))
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.Catalog.Resources = &contract.Resources{Tasks: []*contract.TektonResource{{
+		Name:     "foo",
+		Filename: "tasks/foo/foo.yaml",
+		Checksum: hex.EncodeToString(sum[:]),
+	}}}
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+
+	// only the first resource would be annotated, the file is rejected
+	_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Strict: true})
+	assert.ErrorContains(t, err, "foo.yaml: several YAML documents, expects a single resource: 2 documents")
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t)))
+}
+
+func TestGenerateFilesystemStepActions(t *testing.T) {
+	const task = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+	const stepAction = "apiVersion: tekton.dev/v1alpha1\nkind: StepAction\nmetadata:\n  name: bar\n"
+	taskSum, stepActionSum := sha256.Sum256([]byte(task)), sha256.Sum256([]byte(stepAction))
+
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		Body(bytes.NewReader(craftArchive(t,
+			archiveEntry{name: "tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: task},
+			archiveEntry{name: "stepactions/bar/bar.yaml", typeflag: tar.TypeReg, content: stepAction},
+		)))
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.Catalog.Resources = &contract.Resources{
+		Tasks: []*contract.TektonResource{{
+			Name:     "foo",
+			Filename: "tasks/foo/foo.yaml",
+			Checksum: hex.EncodeToString(taskSum[:]),
+		}},
+		StepActions: []*contract.TektonResource{{
+			Name:     "bar",
+			Filename: "stepactions/bar/bar.yaml",
+			Checksum: hex.EncodeToString(stepActionSum[:]),
+		}},
+	}
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+
+	report, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, contract.TypeStepActions, catalog.Options{Strict: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Added, []string{"sbr-golang@0.5.0"})
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t,
+		fs.WithFile(catalog.ManifestFilename, "", fs.MatchAnyFileContent),
+		fs.WithDir("stepactions", fs.WithDir("bar", fs.WithDir("0.5.0",
+			fs.WithFile("bar.yaml", "", fs.MatchAnyFileContent)))),
+	)))
+	payload, err := os.ReadFile(dir.Join("stepactions", "bar", "0.5.0", "bar.yaml"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(payload), "kind: StepAction"))
+}
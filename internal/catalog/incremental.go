@@ -0,0 +1,149 @@
+package catalog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Report summarizes a catalog generation, versions are listed as "repository@version".
+type Report struct {
+	Added     []string // versions not present in the target before
+	Updated   []string // versions present in the target, with missing or different resources
+	Untouched []string // versions already up to date, not downloaded again
+	Failed    []string // versions which couldn't be fetched
+}
+
+// String renders the report summary in a human readable way.
+func (r Report) String() string {
+	s := fmt.Sprintf("%d added, %d updated, %d untouched", len(r.Added), len(r.Updated), len(r.Untouched))
+	if len(r.Failed) > 0 {
+		s = fmt.Sprintf("%s, %d failed", s, len(r.Failed))
+	}
+	return s
+}
+
+// versionState state of a release version in the target tree.
+type versionState int
+
+const (
+	// versionAbsent none of the version resources are present in the target.
+	versionAbsent versionState = iota
+	// versionChanged some of the version resources are missing or differ from the contract.
+	versionChanged
+	// versionUpToDate all the version resources are present and match the contract checksums,
+	// they were written with the current verification policy.
+	versionUpToDate
+)
+
+// resourceTarget returns the location of the resource file in the target tree, the resource
+// "{type}/{name}/{file}" is extracted on "{type}/{name}/{version}/{file}".
+func resourceTarget(dst, filename, version string) string {
+	return filepath.Join(dst, filepath.Dir(filename), version, filepath.Base(filename))
+}
+
+// localResourceTarget returns the resource target, when it's contained in dst.
+func localResourceTarget(dst, filename, version string) (string, bool) {
+	rel := filepath.Join(filepath.Dir(filename), version, filepath.Base(filename))
+	if !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.Join(dst, rel), true
+}
+
+// verificationPolicy describes how the release resources are verified before writing them, the
+// pinned public key digest and whether signatures are required. Empty when not verified.
+func verificationPolicy(release Release) string {
+	policy := []string{}
+	if release.PublicKey != "" {
+		sum := sha256.Sum256([]byte(release.PublicKey))
+		policy = append(policy, "public-key:"+hex.EncodeToString(sum[:]))
+	}
+	if release.RequireSignatures {
+		policy = append(policy, "require-signatures")
+	}
+	return strings.Join(policy, ",")
+}
+
+// inspectVersion compares the release resources present in the target tree with the contract
+// checksums, ignoring the annotations added while extracting them. The resources written with
+// another verification policy than the current one, recorded by path on the manifest, are
+// stale.
+func inspectVersion(dst string, release Release, version, resourceType string, policies map[string]string) (versionState, error) {
+	resources := getResourcesFromType(release, resourceType)
+	if len(resources) == 0 {
+		return versionAbsent, nil
+	}
+	present, matching := 0, 0
+	annotations := releaseAnnotations(release)
+	policy := verificationPolicy(release)
+	for _, r := range resources {
+		payload, err := os.ReadFile(resourceTarget(dst, r.Filename, version))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return versionAbsent, err
+		}
+		present++
+		if policies[filepath.Join(filepath.Dir(r.Filename), version)] != policy {
+			continue
+		}
+		if matchesChecksum(payload, annotations, r.Checksum) {
+			matching++
+		}
+	}
+	switch {
+	case present == 0:
+		return versionAbsent, nil
+	case matching == len(resources):
+		return versionUpToDate, nil
+	default:
+		return versionChanged, nil
+	}
+}
+
+// matchesChecksum asserts the extracted file matches the checksum of the original file, either
+// as-is or without the annotations added after extracting it.
+func matchesChecksum(payload []byte, annotations []annotation, checksum string) bool {
+	for _, candidate := range [][]byte{payload, removeAnnotations(payload, annotations)} {
+		// the file is rewritten line by line, a missing final new line is added
+		for _, c := range [][]byte{candidate, bytes.TrimSuffix(candidate, []byte("\n"))} {
+			sum := sha256.Sum256(c)
+			if hex.EncodeToString(sum[:]) == checksum {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeAnnotations removes the annotation lines added by addAnnotationsToTask, right after the
+// first annotations block line.
+func removeAnnotations(payload []byte, annotations []annotation) []byte {
+	annotationsPattern := regexp.MustCompile(`^\s+annotations:\s*$`)
+	added := map[string]bool{}
+	for _, a := range annotations {
+		added[fmt.Sprintf("    %s: \"%s\"", a.key, a.value)] = true
+	}
+	lines := strings.SplitAfter(string(payload), "\n")
+	kept := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		kept = append(kept, lines[i])
+		if !annotationsPattern.MatchString(strings.TrimSuffix(lines[i], "\n")) {
+			continue
+		}
+		for i+1 < len(lines) && added[strings.TrimSuffix(lines[i+1], "\n")] {
+			i++
+		}
+		kept = append(kept, lines[i+1:]...)
+		break
+	}
+	return []byte(strings.Join(kept, ""))
+}
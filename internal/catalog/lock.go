@@ -0,0 +1,163 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"sigs.k8s.io/yaml"
+)
+
+// ErrLockMismatch marks upstream contents differing from the lock file.
+var ErrLockMismatch = errors.New("upstream content differs from the lock file")
+
+// Lock records the releases resolved for each repository of the externals configuration, and
+// the digest of their contract and resources tarball, to generate the same catalog later on.
+type Lock struct {
+	Repositories []LockedRepository `json:"repositories"`
+}
+
+// LockedRepository releases resolved for the repository.
+type LockedRepository struct {
+	Name     string          `json:"name"`
+	URL      string          `json:"url"`
+	Releases []LockedRelease `json:"releases"`
+}
+
+// LockedRelease the release tag, and the location and digest of its contract and tarball.
+type LockedRelease struct {
+	Tag       string      `json:"tag"`
+	Contract  LockedAsset `json:"contract"`
+	Resources LockedAsset `json:"resources"`
+}
+
+// LockedAsset location and SHA256 digest of a release asset. The location is informative, the
+// same asset is downloaded from the API when a token is available, the release tag and the
+// digest identify it.
+type LockedAsset struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// LockFilename returns the lock file location of the externals configuration, the same file
+// name with the ".lock" extension ("externals.yaml" is locked by "externals.lock").
+func LockFilename(externals string) string {
+	return strings.TrimSuffix(externals, filepath.Ext(externals)) + ".lock"
+}
+
+// NewLock records the releases of the catalog fetched from the externals configuration, the
+// resources tarballs are downloaded to compute their digest.
+func NewLock(ctx context.Context, e config.External, c Catalog, opts Options) (*Lock, error) {
+	type job struct {
+		repository, release int
+		uri                 string
+	}
+	l := &Lock{Repositories: []LockedRepository{}}
+	jobs := []job{}
+	for _, r := range e.Repositories {
+		name := repositoryName(r)
+		repository := LockedRepository{Name: name, URL: r.URL, Releases: []LockedRelease{}}
+		for _, version := range sortedKeys(c.Repositories[name]) {
+			release := c.Repositories[name][version]
+			jobs = append(jobs, job{
+				repository: len(l.Repositories),
+				release:    len(repository.Releases),
+				uri:        release.ResourcesURI,
+			})
+			repository.Releases = append(repository.Releases, LockedRelease{
+				Tag:       release.Tag,
+				Contract:  LockedAsset{URL: release.ContractURI, SHA256: release.ContractSHA256},
+				Resources: LockedAsset{URL: release.ResourcesURI},
+			})
+		}
+		l.Repositories = append(l.Repositories, repository)
+	}
+
+	err := forEach(ctx, opts.Parallelism, len(jobs), func(ctx context.Context, i int) error {
+		ctx, cancel := opts.withTimeout(ctx)
+		defer cancel()
+		digest, err := opts.Clients.Digest(ctx, jobs[i].uri)
+		if err != nil {
+			return fmt.Errorf("failed to lock resources %s: %w", jobs[i].uri, err)
+		}
+		l.Repositories[jobs[i].repository].Releases[jobs[i].release].Resources.SHA256 = digest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadLock reads the lock file.
+func LoadLock(filename string) (*Lock, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not load lock file %s: %w", filename, err)
+	}
+	l := &Lock{}
+	if err := yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("could not load lock file %s: %w", filename, err)
+	}
+	return l, nil
+}
+
+// Save writes the lock file.
+func (l *Lock) Save(filename string) error {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644) //nolint:gosec
+}
+
+// Freeze asserts the catalog holds the very same releases and contracts than the lock, and
+// pins the resources tarball digests so they are verified when generating the catalog. The
+// assets are identified by the release tag and their digest, whatever their download location.
+// All the differences are reported at once.
+func (l *Lock) Freeze(c Catalog) error {
+	diffs := []string{}
+	locked := map[string]bool{}
+	for _, r := range l.Repositories {
+		locked[r.Name] = true
+		repository, ok := c.Repositories[r.Name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("repository %s is not in the externals configuration", r.Name))
+			continue
+		}
+		tags := map[string]bool{}
+		for _, lr := range r.Releases {
+			tags[lr.Tag] = true
+			version := strings.TrimPrefix(lr.Tag, "v")
+			release, ok := repository[version]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("%s: release %s is no longer available", r.Name, lr.Tag))
+				continue
+			}
+			if release.ContractSHA256 != lr.Contract.SHA256 {
+				diffs = append(diffs, fmt.Sprintf("%s: contract of %s changed (sha256 %s, locked sha256 %s)",
+					r.Name, lr.Tag, release.ContractSHA256, lr.Contract.SHA256))
+			}
+			release.ResourcesSHA256 = lr.Resources.SHA256
+			repository[version] = release
+		}
+		for _, version := range sortedKeys(repository) {
+			if tag := repository[version].Tag; !tags[tag] {
+				diffs = append(diffs, fmt.Sprintf("%s: release %s is not locked", r.Name, tag))
+			}
+		}
+	}
+	for _, name := range sortedKeys(c.Repositories) {
+		if !locked[name] {
+			diffs = append(diffs, fmt.Sprintf("repository %s is not locked", name))
+		}
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w:\n  - %s", ErrLockMismatch, strings.Join(diffs, "\n  - "))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package catalog_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"gopkg.in/h2non/gock.v1"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+// resourcesSHA256 digest of testdata/resources.tar.gz
+const resourcesSHA256 = "803faf01ed22994bb90dc3e0a56626fbd8993be4c28e394769488ed3d7f287f4"
+
+func lockedCatalog() (config.External, catalog.Catalog) {
+	e := config.External{Repositories: []config.Repository{{
+		Name: "sbr-golang",
+		URL:  "https://fake.host/repo",
+	}}}
+	c := catalog.Catalog{
+		Repositories: map[string]catalog.Repository{
+			"sbr-golang": map[string]catalog.Release{
+				"0.5.0": {
+					Tag:            "v0.5.0",
+					ContractURI:    "https://fake.host/repo/catalog.yaml",
+					ContractSHA256: "0123456789abcdef",
+					ResourcesURI:   "https://fake.host/repo/resources.tar.gz",
+					Catalog: contract.Catalog{
+						Resources: &contract.Resources{
+							Tasks: []*contract.TektonResource{{
+								Name:     "go-crane-image",
+								Version:  "0.5.0",
+								Filename: "tasks/go-crane-image/go-crane-image.yaml",
+								Checksum: "9b1f8e2ecbb5795727de93a6b95bbed2a4f44871f0f0ded6a2d8a04b2283a2b9",
+							}},
+						},
+					},
+				},
+			},
+		},
+	}
+	return e, c
+}
+
+func TestLock(t *testing.T) {
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		File("testdata/resources.tar.gz")
+
+	e, c := lockedCatalog()
+	l, err := catalog.NewLock(context.Background(), e, c, catalog.Options{Parallelism: 2})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, l, &catalog.Lock{Repositories: []catalog.LockedRepository{{
+		Name: "sbr-golang",
+		URL:  "https://fake.host/repo",
+		Releases: []catalog.LockedRelease{{
+			Tag:       "v0.5.0",
+			Contract:  catalog.LockedAsset{URL: "https://fake.host/repo/catalog.yaml", SHA256: "0123456789abcdef"},
+			Resources: catalog.LockedAsset{URL: "https://fake.host/repo/resources.tar.gz", SHA256: resourcesSHA256},
+		}},
+	}}})
+
+	dir := fs.NewDir(t, "lock")
+	defer dir.Remove()
+	filename := catalog.LockFilename(filepath.Join(dir.Path(), "externals.yaml"))
+	assert.Equal(t, filepath.Base(filename), "externals.lock")
+	assert.NilError(t, l.Save(filename))
+	loaded, err := catalog.LoadLock(filename)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, loaded, l)
+
+	assert.NilError(t, loaded.Freeze(c))
+	assert.Equal(t, c.Repositories["sbr-golang"]["0.5.0"].ResourcesSHA256, resourcesSHA256)
+
+	// with a token the assets are downloaded from the API, the lock still holds
+	_, c = lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.ContractURI = "https://api.fake.host/repos/org/repo/releases/assets/1"
+	release.ResourcesURI = "https://api.fake.host/repos/org/repo/releases/assets/2"
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+	assert.NilError(t, loaded.Freeze(c))
+	assert.Equal(t, c.Repositories["sbr-golang"]["0.5.0"].ResourcesSHA256, resourcesSHA256)
+}
+
+func TestLockFreezeMismatch(t *testing.T) {
+	_, c := lockedCatalog()
+	l := &catalog.Lock{Repositories: []catalog.LockedRepository{{
+		Name: "sbr-golang",
+		Releases: []catalog.LockedRelease{{
+			Tag:       "v0.5.0",
+			Contract:  catalog.LockedAsset{URL: "https://fake.host/repo/catalog.yaml", SHA256: "fedcba9876543210"},
+			Resources: catalog.LockedAsset{URL: "https://fake.host/repo/resources.tar.gz", SHA256: resourcesSHA256},
+		}, {
+			Tag: "v0.4.0",
+		}},
+	}, {
+		Name: "removed",
+	}}}
+
+	err := l.Freeze(c)
+	assert.ErrorIs(t, err, catalog.ErrLockMismatch)
+	assert.ErrorContains(t, err, "contract of v0.5.0 changed")
+	assert.ErrorContains(t, err, "release v0.4.0 is no longer available")
+	assert.ErrorContains(t, err, "repository removed is not in the externals configuration")
+}
+
+func TestGenerateFilesystemFrozenMismatch(t *testing.T) {
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Reply(200).
+		File("testdata/resources.tar.gz")
+
+	dir := fs.NewDir(t, "catalog")
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.ResourcesSHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+
+	_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	assert.ErrorIs(t, err, catalog.ErrLockMismatch)
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t)))
+}
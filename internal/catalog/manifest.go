@@ -0,0 +1,184 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ManifestFilename file tracking the version folders generated in the catalog target, only
+// the folders listed are ever pruned.
+const ManifestFilename = ".catalog-cd-manifest.yaml"
+
+// Manifest lists the version folders generated in the catalog target.
+type Manifest struct {
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry a version folder generated in the target ("tasks/{name}/{version}"), the
+// release it comes from and the verification policy its resources were written with.
+type ManifestEntry struct {
+	Path       string `json:"path"`
+	Repository string `json:"repository"`
+	Version    string `json:"version"`
+	Policy     string `json:"policy,omitempty"`
+}
+
+// String renders the entry in a human readable way.
+func (e ManifestEntry) String() string {
+	return fmt.Sprintf("%s (%s@%s)", e.Path, e.Repository, e.Version)
+}
+
+// loadManifest reads the manifest of the target, empty when the target has none.
+func loadManifest(dst string) (Manifest, error) {
+	m := Manifest{}
+	data, err := os.ReadFile(filepath.Join(dst, ManifestFilename))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest %s: %w", filepath.Join(dst, ManifestFilename), err)
+	}
+	return m, nil
+}
+
+// save writes the manifest in the target, the entries sorted by path.
+func (m Manifest) save(dst string) error {
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	// the manifest is replaced rather than rewritten, it may be linked to the target one, see
+	// NewStaging
+	f, err := os.CreateTemp(dst, ManifestFilename+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil { //nolint:gosec
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dst, ManifestFilename))
+}
+
+// policies returns the verification policy of each entry, keyed by path.
+func (m Manifest) policies() map[string]string {
+	policies := make(map[string]string, len(m.Entries))
+	for _, e := range m.Entries {
+		policies[e.Path] = e.Policy
+	}
+	return policies
+}
+
+// merge returns the manifest with the informed entries, replacing the entries on the same path.
+func (m Manifest) merge(entries []ManifestEntry) Manifest {
+	byPath := map[string]ManifestEntry{}
+	for _, e := range m.Entries {
+		byPath[e.Path] = e
+	}
+	for _, e := range entries {
+		byPath[e.Path] = e
+	}
+	merged := Manifest{Entries: make([]ManifestEntry, 0, len(byPath))}
+	for _, path := range sortedKeys(byPath) {
+		merged.Entries = append(merged.Entries, byPath[path])
+	}
+	return merged
+}
+
+// expectedEntries returns the version folders of the catalog resources, keyed by path.
+func expectedEntries(c Catalog, resourceType string) map[string]ManifestEntry {
+	entries := map[string]ManifestEntry{}
+	for name, repository := range c.Repositories {
+		for version, release := range repository {
+			for _, r := range getResourcesFromType(release, resourceType) {
+				path := filepath.Join(filepath.Dir(r.Filename), version)
+				entries[path] = ManifestEntry{
+					Path:       path,
+					Repository: name,
+					Version:    version,
+					Policy:     verificationPolicy(release),
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// recordManifest adds the version folders of the catalog to the target manifest. The versions
+// which failed ("repository@version") keep the policy previously recorded, their resources were
+// not written with the current one.
+func recordManifest(dst string, c Catalog, resourceType string, failed map[string]bool) error {
+	m, err := loadManifest(dst)
+	if err != nil {
+		return err
+	}
+	recorded := m.policies()
+	expected := expectedEntries(c, resourceType)
+	entries := make([]ManifestEntry, 0, len(expected))
+	for _, path := range sortedKeys(expected) {
+		e := expected[path]
+		if failed[fmt.Sprintf("%s@%s", e.Repository, e.Version)] {
+			e.Policy = recorded[path]
+		}
+		entries = append(entries, e)
+	}
+	return m.merge(entries).save(dst)
+}
+
+// Prune removes the version folders of the target which aren't part of the catalog anymore (for
+// instance ignored or deleted upstream), only the folders listed by the target manifest are
+// considered. Returns the folders pruned, on dry-run they are only listed.
+func Prune(dst string, c Catalog, resourceType string, dryRun bool) ([]ManifestEntry, error) {
+	m, err := loadManifest(dst)
+	if err != nil {
+		return nil, err
+	}
+	expected := expectedEntries(c, resourceType)
+	kept := Manifest{Entries: []ManifestEntry{}}
+	pruned := []ManifestEntry{}
+	for _, e := range m.Entries {
+		if _, ok := expected[e.Path]; ok {
+			kept.Entries = append(kept.Entries, e)
+			continue
+		}
+		if !filepath.IsLocal(e.Path) {
+			return nil, fmt.Errorf("invalid manifest entry %q, escaping the target %s", e.Path, dst)
+		}
+		pruned = append(pruned, e)
+	}
+	if dryRun || len(pruned) == 0 {
+		return pruned, nil
+	}
+
+	for _, e := range pruned {
+		if err := os.RemoveAll(filepath.Join(dst, e.Path)); err != nil {
+			return nil, err
+		}
+		// the resource folder is removed with its last version
+		for dir := filepath.Dir(e.Path); dir != "."; dir = filepath.Dir(dir) {
+			if err := os.Remove(filepath.Join(dst, dir)); err != nil {
+				break
+			}
+		}
+	}
+	return pruned, kept.save(dst)
+}
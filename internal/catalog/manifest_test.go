@@ -0,0 +1,66 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"gopkg.in/h2non/gock.v1"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestPrune(t *testing.T) {
+	t.Cleanup(gock.Off)
+	gock.New("https://fake.host").
+		Get("repo/resources.tar.gz").
+		Times(2).
+		Reply(200).
+		File("testdata/resources.tar.gz")
+
+	// content not generated by catalog-cd, never pruned
+	dir := fs.NewDir(t, "catalog", fs.WithDir("tasks", fs.WithDir("handmade", fs.WithDir("0.1.0",
+		fs.WithFile("handmade.yaml", "kind: Task")))))
+	defer dir.Remove()
+
+	_, c := lockedCatalog()
+	previous := c.Repositories["sbr-golang"]["0.5.0"]
+	c.Repositories["sbr-golang"]["0.4.0"] = previous
+	_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	stale := filepath.Join(dir.Path(), "tasks", "go-crane-image", "0.4.0")
+	_, err = os.Stat(stale)
+	assert.NilError(t, err)
+
+	// 0.4.0 is now ignored
+	delete(c.Repositories["sbr-golang"], "0.4.0")
+	pruned, err := catalog.Prune(dir.Path(), c, "tasks", true)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pruned, []catalog.ManifestEntry{{
+		Path:       filepath.Join("tasks", "go-crane-image", "0.4.0"),
+		Repository: "sbr-golang",
+		Version:    "0.4.0",
+	}})
+	_, err = os.Stat(stale)
+	assert.NilError(t, err, "dry-run shouldn't have removed %s", stale)
+
+	pruned, err = catalog.Prune(dir.Path(), c, "tasks", false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pruned), 1)
+	_, err = os.Stat(stale)
+	assert.Assert(t, os.IsNotExist(err))
+	for _, kept := range []string{
+		filepath.Join("tasks", "go-crane-image", "0.5.0", "go-crane-image.yaml"),
+		filepath.Join("tasks", "handmade", "0.1.0", "handmade.yaml"),
+	} {
+		_, err = os.Stat(filepath.Join(dir.Path(), kept))
+		assert.NilError(t, err)
+	}
+
+	// nothing left to prune
+	pruned, err = catalog.Prune(dir.Path(), c, "tasks", false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pruned), 0)
+}
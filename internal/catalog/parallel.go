@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"golang.org/x/sync/errgroup"
+)
+
+// Options configures how the catalog is fetched and generated.
+type Options struct {
+	// Parallelism amount of concurrent requests, sequential when lower than two.
+	Parallelism int
+	// Strict reports every failure, instead of stopping on the first error when fetching the
+	// catalog or skipping the versions which couldn't be fetched when generating it.
+	Strict bool
+	// MaxArchiveEntries maximum amount of entries of a resources tarball, DefaultMaxArchiveEntries
+	// when zero.
+	MaxArchiveEntries int
+	// MaxArchiveBytes maximum amount of bytes extracted from a resources tarball, and of the
+	// tarball itself when it's verified before extracting it, DefaultMaxArchiveBytes when zero.
+	MaxArchiveBytes int64
+	// Timeout maximum duration of each request (listing releases, downloading a contract or
+	// a tarball), no timeout when zero.
+	Timeout time.Duration
+	// Clients resolves the credentials of each host, listing the releases and downloading
+	// their assets. When nil, they are built out of the externals hosts configuration.
+	Clients *fetcher.Clients
+}
+
+const (
+	// DefaultMaxArchiveEntries default maximum amount of entries of a resources tarball.
+	DefaultMaxArchiveEntries = 10000
+	// DefaultMaxArchiveBytes default maximum amount of bytes extracted from a resources tarball.
+	DefaultMaxArchiveBytes = 100 << 20
+)
+
+// archiveLimits returns the resources tarball limits, using the defaults when not set.
+func (o Options) archiveLimits() archiveLimits {
+	l := archiveLimits{entries: o.MaxArchiveEntries, bytes: o.MaxArchiveBytes}
+	if l.entries <= 0 {
+		l.entries = DefaultMaxArchiveEntries
+	}
+	if l.bytes <= 0 {
+		l.bytes = DefaultMaxArchiveBytes
+	}
+	return l
+}
+
+// withTimeout derives the context using the configured per-request timeout.
+func (o Options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.Timeout)
+}
+
+// forEach runs fn for each index in [0, n) using at most "parallelism" goroutines. The first
+// error returned cancels the context shared by the remaining calls, and is returned.
+func forEach(ctx context.Context, parallelism, n int, fn func(context.Context, int) error) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(parallelism, 1))
+	for i := 0; i < n; i++ {
+		i := i
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fn(ctx, i)
+		})
+	}
+	return g.Wait()
+}
+
+// forEachCollect runs fn for each index in [0, n) like forEach, without stopping on errors.
+// Returns all the errors joined, in index order.
+func forEachCollect(ctx context.Context, parallelism, n int, fn func(context.Context, int) error) error {
+	errs := make([]error, n)
+	err := forEach(ctx, parallelism, n, func(ctx context.Context, i int) error {
+		errs[i] = fn(ctx, i)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// run returns the function running the tasks, collecting every error in strict mode.
+func (o Options) run() func(context.Context, int, int, func(context.Context, int) error) error {
+	if o.Strict {
+		return forEachCollect
+	}
+	return forEach
+}
@@ -0,0 +1,246 @@
+package catalog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyOptions settings of the generated VerificationPolicy objects.
+type PolicyOptions struct {
+	// Namespace of the policies, the namespace of the verified resources.
+	Namespace string
+	// Mode policy mode, enforce or warn.
+	Mode v1alpha1.ModeType
+	// PinnedOnly only the public-keys pinned by the externals configuration are trusted, the
+	// repositories without are skipped instead of trusting the keys of their contracts.
+	PinnedOnly bool
+}
+
+// kmsSchemes key references resolved by a KMS, as supported by cosign.
+var kmsSchemes = []string{"awskms://", "azurekms://", "gcpkms://", "hashivault://"}
+
+// k8sScheme key reference on a Kubernetes Secret, "k8s://{namespace}/{name}".
+const k8sScheme = "k8s://"
+
+// Policies returns a Tekton VerificationPolicy for each repository of the catalog, trusting the
+// keys the catalog trusts: the public-key pinned by the externals configuration, or else the
+// attestation keys of the repository contracts, which are not verified. Trusting the contract
+// keys is reported on the informed writer, it's disabled with PinnedOnly. The resources are
+// matched by the repository source URL, and the keys are embedded inline. Repositories without
+// usable keys are skipped, and reported on the informed writer.
+func Policies(out io.Writer, c Catalog, opts PolicyOptions) ([]v1alpha1.VerificationPolicy, error) {
+	if opts.Mode == "" {
+		opts.Mode = v1alpha1.ModeEnforce
+	}
+	if opts.Mode != v1alpha1.ModeEnforce && opts.Mode != v1alpha1.ModeWarn {
+		return nil, fmt.Errorf("invalid policy mode %q, expects %q or %q", opts.Mode, v1alpha1.ModeEnforce, v1alpha1.ModeWarn)
+	}
+	policies := []v1alpha1.VerificationPolicy{}
+	for _, name := range sortedKeys(c.Repositories) {
+		releases := c.Repositories[name]
+		source := ""
+		authorities := []v1alpha1.Authority{}
+		seen, names := map[string]bool{}, map[string]bool{}
+		unpinned := false // trusting the keys of the contracts
+		for _, version := range sortedKeys(releases) {
+			release := releases[version]
+			if source == "" {
+				source = extractRepositoryURL(release.ResourcesURI)
+			}
+			for _, k := range trustedKeys(release, opts.PinnedOnly) {
+				ref, err := keyRef(k.key, k.pinned)
+				if err != nil {
+					return nil, fmt.Errorf("repository %s: %w", name, err)
+				}
+				if ref == nil {
+					fmt.Fprintf(out, "# WARNING: %s@%s key %q is not inline nor a KMS reference, skipped\n", name, version, k.name)
+					continue
+				}
+				id := ref.Data + ref.KMS
+				if ref.SecretRef != nil {
+					id = ref.SecretRef.Namespace + "/" + ref.SecretRef.Name
+				}
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+				unpinned = unpinned || !k.pinned
+				authorities = append(authorities, v1alpha1.Authority{Name: authorityName(k.name, names), Key: ref})
+			}
+		}
+		switch {
+		case source == "":
+			fmt.Fprintf(out, "# WARNING: %s has no source URL, skipped\n", name)
+			continue
+		case len(authorities) == 0:
+			fmt.Fprintf(out, "# WARNING: %s has no public key, skipped\n", name)
+			continue
+		case unpinned:
+			fmt.Fprintf(out, "# WARNING: %s has no pinned public-key, trusting the keys of its contracts which are not verified\n", name)
+		}
+		policies = append(policies, v1alpha1.VerificationPolicy{
+			TypeMeta: metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "VerificationPolicy"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      policyName(name),
+				Namespace: opts.Namespace,
+				Annotations: map[string]string{
+					sourceAnnotation: source,
+				},
+			},
+			Spec: v1alpha1.VerificationPolicySpec{
+				Resources:   []v1alpha1.ResourcePattern{{Pattern: resourcePattern(source)}},
+				Authorities: authorities,
+				Mode:        opts.Mode,
+			},
+		})
+	}
+	return policies, nil
+}
+
+// trustedKey key trusted for a release, named after its origin.
+type trustedKey struct {
+	name   string
+	key    string
+	pinned bool
+}
+
+// trustedKeys returns the keys the catalog trusts for the release, the pinned public-key takes
+// precedence over the keys informed by the contract, which are ignored when pinnedOnly.
+func trustedKeys(release Release, pinnedOnly bool) []trustedKey {
+	if release.PublicKey != "" {
+		return []trustedKey{{name: "public-key", key: release.PublicKey, pinned: true}}
+	}
+	a := release.Catalog.Attestation
+	if a == nil || pinnedOnly {
+		return nil
+	}
+	keys := []trustedKey{}
+	if a.PublicKey != "" {
+		keys = append(keys, trustedKey{name: "publickey", key: a.PublicKey})
+	}
+	for _, k := range a.PublicKeys {
+		keys = append(keys, trustedKey{name: k.ID, key: k.Key})
+	}
+	return keys
+}
+
+// keyRef returns the policy key reference, the inline PEM, a KMS URI or a Kubernetes Secret.
+// The pinned key file is read, as it's local to the externals configuration, the contract key
+// files are local to the upstream repository and nil is returned.
+func keyRef(key string, pinned bool) (*v1alpha1.KeyRef, error) {
+	switch {
+	case attestation.InlineKey(key):
+		return &v1alpha1.KeyRef{Data: strings.TrimSpace(key) + "\n", HashAlgorithm: "sha256"}, nil
+	case strings.HasPrefix(key, k8sScheme):
+		namespace, name, ok := strings.Cut(strings.TrimPrefix(key, k8sScheme), "/")
+		if !ok {
+			namespace, name = "", namespace
+		}
+		return &v1alpha1.KeyRef{
+			SecretRef:     &corev1.SecretReference{Namespace: namespace, Name: name},
+			HashAlgorithm: "sha256",
+		}, nil
+	}
+	for _, scheme := range kmsSchemes {
+		if strings.HasPrefix(key, scheme) {
+			return &v1alpha1.KeyRef{KMS: key, HashAlgorithm: "sha256"}, nil
+		}
+	}
+	if !pinned {
+		return nil, nil
+	}
+	data, err := os.ReadFile(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not read public-key: %w", err)
+	}
+	if !attestation.InlineKey(string(data)) {
+		return nil, fmt.Errorf("public-key %s is not PEM encoded", key)
+	}
+	return &v1alpha1.KeyRef{Data: strings.TrimSpace(string(data)) + "\n", HashAlgorithm: "sha256"}, nil
+}
+
+// authorityName returns an unique authority name, numbered when empty or already taken.
+func authorityName(name string, taken map[string]bool) string {
+	if name == "" {
+		name = "key"
+	}
+	unique := name
+	for i := 1; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// invalidNameChars characters not allowed on a Kubernetes object name.
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// policyName returns the policy name of the repository, a valid Kubernetes object name.
+func policyName(repository string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(repository), "-"), "-")
+	name = "catalog-cd-" + name
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength], "-")
+	}
+	return name
+}
+
+// resourcePattern returns the regular expression matching the resources source (Tekton
+// "refSource.uri") of the repository, either fetched with the git or http resolvers, or the
+// bundles of the OCI repository.
+func resourcePattern(source string) string {
+	if strings.HasPrefix(source, oci.Scheme) {
+		return fmt.Sprintf(`^(%s)?%s([:@].*)?$`, regexp.QuoteMeta(oci.Scheme),
+			regexp.QuoteMeta(strings.TrimPrefix(source, oci.Scheme)))
+	}
+	return fmt.Sprintf(`^(git\+)?%s(\.git)?([/@].*)?$`, regexp.QuoteMeta(strings.TrimSuffix(source, ".git")))
+}
+
+// WritePolicies writes the policies as a multi-document YAML.
+func WritePolicies(w io.Writer, policies []v1alpha1.VerificationPolicy) error {
+	for _, p := range policies {
+		data, err := yaml.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GeneratedCatalog returns the releases of the catalog generated in the target, as tracked by
+// its manifest file.
+func GeneratedCatalog(c Catalog, dst string) (Catalog, error) {
+	m, err := loadManifest(dst)
+	if err != nil {
+		return Catalog{}, err
+	}
+	if len(m.Entries) == 0 {
+		return Catalog{}, fmt.Errorf("no catalog generated in %s, %s is missing or empty", dst, ManifestFilename)
+	}
+	generated := Catalog{Repositories: map[string]Repository{}}
+	for _, e := range m.Entries {
+		release, ok := c.Repositories[e.Repository][e.Version]
+		if !ok {
+			continue
+		}
+		if generated.Repositories[e.Repository] == nil {
+			generated.Repositories[e.Repository] = Repository{}
+		}
+		generated.Repositories[e.Repository][e.Version] = release
+	}
+	return generated, nil
+}
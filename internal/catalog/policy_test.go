@@ -0,0 +1,149 @@
+package catalog_test
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const policyKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE4Q9Ptk6Ok0jpa0Ww9g3wGrMoV0Xn
+Vfx4fEwCs6yrDVR4FJgzNaWC3EJTz2hiXFvTz8hdl3JR1G8P3gmrmYbfIQ==
+-----END PUBLIC KEY-----
+`
+
+func policyCatalog(pinned string) catalog.Catalog {
+	return catalog.Catalog{Repositories: map[string]catalog.Repository{
+		"pinned": {
+			"0.1.0": catalog.Release{
+				ResourcesURI: "https://github.com/org/pinned/releases/download/v0.1.0/resources.tar.gz",
+				PublicKey:    pinned,
+				Catalog: contract.Catalog{Attestation: &contract.Attestation{
+					PublicKey: "awskms:///ignored-as-pinned",
+				}},
+			},
+		},
+		"rotated": {
+			"0.1.0": catalog.Release{
+				ResourcesURI: "https://github.com/org/rotated/releases/download/v0.1.0/resources.tar.gz",
+				Catalog: contract.Catalog{Attestation: &contract.Attestation{
+					PublicKey: "cosign.pub",
+					PublicKeys: []contract.PublicKey{
+						{ID: "2024", Key: policyKey},
+						{ID: "kms", Key: "gcpkms://projects/p/locations/l/keyRings/r/cryptoKeys/k"},
+					},
+				}},
+			},
+			"0.2.0": catalog.Release{
+				ResourcesURI: "https://github.com/org/rotated/releases/download/v0.2.0/resources.tar.gz",
+				Catalog: contract.Catalog{Attestation: &contract.Attestation{
+					PublicKeys: []contract.PublicKey{
+						{ID: "2024", Key: policyKey},
+						{ID: "2025", Key: "k8s://tekton-pipelines/signing-secret"},
+					},
+				}},
+			},
+		},
+		"unsigned": {
+			"0.1.0": catalog.Release{
+				ResourcesURI: "https://github.com/org/unsigned/releases/download/v0.1.0/resources.tar.gz",
+			},
+		},
+	}}
+}
+
+func TestPolicies(t *testing.T) {
+	keys := fs.NewDir(t, "keys", fs.WithFile("cosign.pub", policyKey))
+	defer keys.Remove()
+
+	var out bytes.Buffer
+	policies, err := catalog.Policies(&out, policyCatalog(keys.Join("cosign.pub")), catalog.PolicyOptions{Namespace: "tasks"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(policies), 2)
+	assert.Assert(t, strings.Contains(out.String(), `rotated@0.1.0 key "publickey" is not inline nor a KMS reference, skipped`))
+	assert.Assert(t, strings.Contains(out.String(), "unsigned has no public key, skipped"))
+	assert.Assert(t, strings.Contains(out.String(), "rotated has no pinned public-key, trusting the keys of its contracts"))
+	assert.Assert(t, !strings.Contains(out.String(), "pinned has no pinned public-key"))
+
+	// the pinned key takes precedence, its file is embedded
+	pinned := policies[0]
+	assert.Equal(t, pinned.Name, "catalog-cd-pinned")
+	assert.Equal(t, pinned.Namespace, "tasks")
+	assert.Equal(t, pinned.Spec.Mode, v1alpha1.ModeEnforce)
+	assert.Equal(t, len(pinned.Spec.Authorities), 1)
+	assert.Equal(t, pinned.Spec.Authorities[0].Name, "public-key")
+	assert.Equal(t, pinned.Spec.Authorities[0].Key.Data, policyKey)
+
+	// the contract keys of every release, deduplicated
+	rotated := policies[1]
+	names := []string{}
+	for _, a := range rotated.Spec.Authorities {
+		names = append(names, a.Name)
+	}
+	assert.DeepEqual(t, names, []string{"2024", "kms", "2025"})
+	assert.Equal(t, rotated.Spec.Authorities[1].Key.KMS, "gcpkms://projects/p/locations/l/keyRings/r/cryptoKeys/k")
+	assert.Equal(t, rotated.Spec.Authorities[2].Key.SecretRef.Namespace, "tekton-pipelines")
+	assert.Equal(t, rotated.Spec.Authorities[2].Key.SecretRef.Name, "signing-secret")
+
+	// the resources are matched by the repository source
+	pattern := regexp.MustCompile(rotated.Spec.Resources[0].Pattern)
+	for _, uri := range []string{
+		"https://github.com/org/rotated",
+		"git+https://github.com/org/rotated.git",
+		"git+https://github.com/org/rotated.git@v0.2.0",
+		"https://github.com/org/rotated/raw/v0.2.0/tasks/foo/foo.yaml",
+	} {
+		assert.Assert(t, pattern.MatchString(uri), uri)
+	}
+	for _, uri := range []string{
+		"https://github.com/org/rotated-fork",
+		"https://github.com/evil/org/rotated",
+	} {
+		assert.Assert(t, !pattern.MatchString(uri), uri)
+	}
+
+	var manifests bytes.Buffer
+	assert.NilError(t, catalog.WritePolicies(&manifests, policies))
+	assert.Assert(t, strings.HasPrefix(manifests.String(), "---\napiVersion: tekton.dev/v1alpha1\nkind: VerificationPolicy\n"))
+	assert.Equal(t, strings.Count(manifests.String(), "kind: VerificationPolicy"), 2)
+
+	// only the pinned keys are trusted
+	out.Reset()
+	policies, err = catalog.Policies(&out, policyCatalog(keys.Join("cosign.pub")), catalog.PolicyOptions{PinnedOnly: true})
+	assert.NilError(t, err)
+	assert.Equal(t, len(policies), 1)
+	assert.Equal(t, policies[0].Name, "catalog-cd-pinned")
+	assert.Assert(t, strings.Contains(out.String(), "rotated has no public key, skipped"))
+
+	_, err = catalog.Policies(&out, policyCatalog(keys.Join("cosign.pub")), catalog.PolicyOptions{Mode: "audit"})
+	assert.ErrorContains(t, err, `invalid policy mode "audit"`)
+	_, err = catalog.Policies(&out, policyCatalog(keys.Join("missing.pub")), catalog.PolicyOptions{})
+	assert.ErrorContains(t, err, "repository pinned: could not read public-key")
+}
+
+func TestGeneratedCatalog(t *testing.T) {
+	dir := fs.NewDir(t, "catalog", fs.WithFile(catalog.ManifestFilename, `entries:
+- path: tasks/foo/0.2.0
+  repository: rotated
+  version: 0.2.0
+`))
+	defer dir.Remove()
+
+	generated, err := catalog.GeneratedCatalog(policyCatalog(""), dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(generated.Repositories), 1)
+	_, ok := generated.Repositories["rotated"]["0.2.0"]
+	assert.Assert(t, ok)
+
+	empty := fs.NewDir(t, "empty")
+	defer empty.Remove()
+	_, err = catalog.GeneratedCatalog(policyCatalog(""), empty.Path())
+	assert.ErrorContains(t, err, "no catalog generated")
+}
@@ -0,0 +1,127 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
+)
+
+// ErrInvalidSignature marks a resource without signature, or with a signature which doesn't
+// match the trusted public key.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// verifySignatures verifies the extracted resources against their signature using the trusted
+// public key, the signature is either the payload informed by the contract, a signature file
+// extracted on sigDir, or embedded on the resource (Tekton trusted resources). The resources
+// must carry the annotations expected by the contract attestation. Every resource is verified
+// before erroring out.
+func verifySignatures(
+	ctx context.Context,
+	out io.Writer,
+	dst, sigDir, publicKey, version string,
+	tektonResources map[string]contract.TektonResource,
+	annotations map[string]string,
+) error {
+	helper, err := attestation.NewAttestation(publicKey)
+	if err != nil {
+		return err
+	}
+	defer helper.Close()
+	helper.WithAnnotations(annotations)
+
+	errs := []error{}
+	for _, name := range sortedKeys(tektonResources) {
+		r := tektonResources[name]
+		target, ok := localResourceTarget(dst, r.Filename, version)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		sigRef, err := signatureRef(sigDir, r)
+		if errors.Is(err, ErrInvalidSignature) {
+			fmt.Fprintf(out, "❌ %s\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if sigRef == "" {
+			// the Tekton trusted resources signature, embedded on the resource
+			if signed, _ := trustedresources.Signed(target); signed {
+				if err := trustedresources.Verify(ctx, []string{publicKey}, annotations, target); err != nil {
+					fmt.Fprintf(out, "❌ %s embedded signature is invalid: %s\n", r.Filename, err)
+					errs = append(errs, fmt.Errorf("%w: %s: %w", ErrInvalidSignature, r.Filename, err))
+					continue
+				}
+				fmt.Fprintf(out, "🔏 %s (embedded)\n", r.Filename)
+				continue
+			}
+			fmt.Fprintf(out, "❌ %s is not signed\n", r.Filename)
+			errs = append(errs, fmt.Errorf("%w: %s is not signed", ErrInvalidSignature, r.Filename))
+			continue
+		}
+		if err := helper.Verify(ctx, target, sigRef); err != nil {
+			fmt.Fprintf(out, "❌ %s signature is invalid: %s\n", r.Filename, err)
+			errs = append(errs, fmt.Errorf("%w: %s: %w", ErrInvalidSignature, r.Filename, err))
+			continue
+		}
+		fmt.Fprintf(out, "🔏 %s\n", r.Filename)
+	}
+	return errors.Join(errs...)
+}
+
+// verifyTarball asserts the resources tarball matches the digest recorded on the (verified)
+// contract, and its detached signature.
+func verifyTarball(ctx context.Context, clients *fetcher.Clients, release Release, digest string, payload []byte) error {
+	tarball := release.Catalog.Tarball
+	if tarball == nil || tarball.Checksum == "" {
+		return fmt.Errorf("%w: the contract doesn't record the resources tarball checksum",
+			fetcher.ErrSignatureRequired)
+	}
+	if tarball.Checksum != digest {
+		return fmt.Errorf("%w: resources %s sha256 is %s, expected %s",
+			contract.ErrTarballChecksum, release.ResourcesURI, digest, tarball.Checksum)
+	}
+	return clients.VerifySignature(ctx, payload, release.ResourcesSignatureURI, release.PublicKey)
+}
+
+// signatureRef returns the location of the resource signature, either the signature file
+// extracted from the tarball or a file holding the signature payload informed by the contract.
+// Returns empty when the resource is not signed.
+func signatureRef(sigDir string, r contract.TektonResource) (string, error) {
+	if r.Signature == "" {
+		return "", nil
+	}
+	name := path.Clean(r.Signature)
+	if filepath.IsLocal(filepath.FromSlash(name)) {
+		extracted := filepath.Join(sigDir, filepath.FromSlash(name))
+		if _, err := os.Stat(extracted); err == nil {
+			return extracted, nil
+		}
+	}
+	if path.Ext(name) == "."+contract.SignatureExtension {
+		return "", fmt.Errorf("%w: signature %s of %s is not part of the resources tarball",
+			ErrInvalidSignature, r.Signature, r.Filename)
+	}
+	f, err := os.CreateTemp(sigDir, "payload-*."+contract.SignatureExtension)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(r.Signature); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,216 @@
+package catalog_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"gopkg.in/h2non/gock.v1"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const signedTask = "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n"
+
+// signingKey generates an ECDSA key, returns the PEM encoded public key and the base64
+// signature of the payload.
+func signingKey(t *testing.T, payload string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), sign(t, key, payload)
+}
+
+// sign returns the base64 signature of the payload.
+func sign(t *testing.T, key *ecdsa.PrivateKey, payload string) string {
+	t.Helper()
+	digest := sha256.Sum256([]byte(payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NilError(t, err)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// signedCatalog returns a catalog with a single task, using the informed trusted key and the
+// resource signature.
+func signedCatalog(publicKey, signature string) catalog.Catalog {
+	sum := sha256.Sum256([]byte(signedTask))
+	_, c := lockedCatalog()
+	release := c.Repositories["sbr-golang"]["0.5.0"]
+	release.PublicKey = publicKey
+	release.Catalog.Resources = &contract.Resources{Tasks: []*contract.TektonResource{{
+		Name:      "foo",
+		Filename:  "tasks/foo/foo.yaml",
+		Checksum:  hex.EncodeToString(sum[:]),
+		Signature: signature,
+	}}}
+	c.Repositories["sbr-golang"]["0.5.0"] = release
+	return c
+}
+
+func TestGenerateFilesystemSignatures(t *testing.T) {
+	publicKey, signature := signingKey(t, signedTask)
+	otherKey, _ := signingKey(t, signedTask)
+	keys := fs.NewDir(t, "keys", fs.WithFile("cosign.pub", publicKey))
+	defer keys.Remove()
+
+	taskEntry := archiveEntry{name: "tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: signedTask}
+	sigEntry := archiveEntry{name: "tasks/foo/foo.yaml.sig", typeflag: tar.TypeReg, content: signature}
+
+	tests := []struct {
+		name        string
+		publicKey   string
+		signature   string
+		annotations map[string]string
+		entries     []archiveEntry
+		err         string
+	}{{
+		name:      "signature file and key file",
+		publicKey: keys.Join("cosign.pub"),
+		signature: "tasks/foo/foo.yaml.sig",
+		entries:   []archiveEntry{taskEntry, sigEntry},
+	}, {
+		name:      "signature payload and inline key",
+		publicKey: publicKey,
+		signature: signature,
+		entries:   []archiveEntry{taskEntry},
+	}, {
+		name:      "unsigned",
+		publicKey: publicKey,
+		entries:   []archiveEntry{taskEntry},
+		err:       "invalid signature: tasks/foo/foo.yaml is not signed",
+	}, {
+		name:      "missing signature file",
+		publicKey: publicKey,
+		signature: "tasks/foo/foo.yaml.sig",
+		entries:   []archiveEntry{taskEntry},
+		err:       "signature tasks/foo/foo.yaml.sig of tasks/foo/foo.yaml is not part of the resources tarball",
+	}, {
+		name:      "untrusted key",
+		publicKey: otherKey,
+		signature: "tasks/foo/foo.yaml.sig",
+		entries:   []archiveEntry{taskEntry, sigEntry},
+		err:       "invalid signature: tasks/foo/foo.yaml",
+	}, {
+		name:        "missing annotation",
+		publicKey:   publicKey,
+		signature:   signature,
+		annotations: map[string]string{"team": "tekton-ecosystem"},
+		entries:     []archiveEntry{taskEntry},
+		err:         `expects annotation team="tekton-ecosystem", got ""`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			gock.New("https://fake.host").
+				Get("repo/resources.tar.gz").
+				Reply(200).
+				Body(bytes.NewReader(craftArchive(t, tt.entries...)))
+
+			dir := fs.NewDir(t, "catalog")
+			defer dir.Remove()
+
+			c := signedCatalog(tt.publicKey, tt.signature)
+			release := c.Repositories["sbr-golang"]["0.5.0"]
+			release.Catalog.Attestation = &contract.Attestation{Annotations: tt.annotations}
+			c.Repositories["sbr-golang"]["0.5.0"] = release
+			_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Strict: true})
+			if tt.err != "" {
+				assert.ErrorIs(t, err, catalog.ErrInvalidSignature)
+				assert.ErrorContains(t, err, tt.err)
+				assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t)))
+				return
+			}
+			assert.NilError(t, err)
+			// the signature files are not part of the catalog
+			entries, err := os.ReadDir(filepath.Join(dir.Path(), "tasks", "foo", "0.5.0"))
+			assert.NilError(t, err)
+			assert.Equal(t, len(entries), 1)
+			assert.Equal(t, entries[0].Name(), "foo.yaml")
+		})
+	}
+}
+
+func TestGenerateFilesystemTarballSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	tarball := craftArchive(t, archiveEntry{name: "tasks/foo/foo.yaml", typeflag: tar.TypeReg, content: signedTask})
+	sum := sha256.Sum256(tarball)
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		checksum  string
+		signature string
+		err       error
+	}{{
+		name:      "signed",
+		checksum:  digest,
+		signature: "repo/resources.tar.gz.sig",
+	}, {
+		name:      "checksum not recorded",
+		signature: "repo/resources.tar.gz.sig",
+		err:       fetcher.ErrSignatureRequired,
+	}, {
+		name:      "checksum mismatch",
+		checksum:  "0000000000000000000000000000000000000000000000000000000000000000",
+		signature: "repo/resources.tar.gz.sig",
+		err:       contract.ErrTarballChecksum,
+	}, {
+		name:     "unsigned",
+		checksum: digest,
+		err:      fetcher.ErrSignatureRequired,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(gock.Off)
+			gock.New("https://fake.host").
+				Get("repo/resources.tar.gz$").
+				Reply(200).
+				Body(bytes.NewReader(tarball))
+			gock.New("https://fake.host").
+				Get("repo/resources.tar.gz.sig").
+				Reply(200).
+				BodyString(sign(t, key, string(tarball)))
+
+			dir := fs.NewDir(t, "catalog")
+			defer dir.Remove()
+
+			c := signedCatalog(publicKey, sign(t, key, signedTask))
+			release := c.Repositories["sbr-golang"]["0.5.0"]
+			release.RequireSignatures = true
+			release.Catalog.Tarball = &contract.Tarball{Name: "resources.tar.gz", Checksum: tt.checksum}
+			if tt.signature != "" {
+				release.ResourcesSignatureURI = "https://fake.host/" + tt.signature
+			}
+			c.Repositories["sbr-golang"]["0.5.0"] = release
+
+			_, err := catalog.GenerateFilesystem(context.Background(), dir.Path(), c, "tasks", catalog.Options{Strict: true})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t)))
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+}
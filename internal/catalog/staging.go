@@ -0,0 +1,168 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Staging is a working copy of the catalog target, the catalog is generated in the staging
+// folder which is then swapped in place of the target, so the target is never left half
+// generated.
+type Staging struct {
+	target string // catalog target
+	dir    string // staging folder, empty once committed
+}
+
+// errExchangeUnsupported the platform or filesystem can't swap two paths atomically.
+var errExchangeUnsupported = errors.New("atomic exchange not supported")
+
+// NewStaging creates the staging folder next to the target (on the same filesystem), holding
+// hard links to the target files so the generation stays incremental without copying the whole
+// catalog, the files are copied when they can't be linked. The generation never modifies files
+// in place, they are replaced, so the target files are left untouched.
+func NewStaging(target string) (*Staging, error) {
+	// the target is resolved, the parent of "." is the target itself
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(filepath.Dir(target), "."+filepath.Base(target)+".staging-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &Staging{target: target, dir: dir}
+
+	mode := fs.FileMode(0o755)
+	info, err := os.Stat(target)
+	switch {
+	case err == nil:
+		mode = info.Mode().Perm()
+		err = linkTree(target, dir)
+	case errors.Is(err, os.ErrNotExist):
+		err = nil
+	}
+	if err == nil {
+		err = os.Chmod(dir, mode)
+	}
+	if err != nil {
+		s.Discard() //nolint:errcheck
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir returns the staging folder, where the catalog is generated.
+func (s *Staging) Dir() string {
+	return s.dir
+}
+
+// Commit swaps the staging folder in place of the target. The swap is atomic where supported
+// (Linux), otherwise the target is moved aside and restored when the staging can't replace it.
+func (s *Staging) Commit() error {
+	if _, err := os.Lstat(s.target); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(s.dir, s.target); err != nil {
+			return err
+		}
+		s.dir = ""
+		return nil
+	} else if err != nil {
+		return err
+	}
+	err := exchange(s.dir, s.target)
+	if errors.Is(err, errExchangeUnsupported) {
+		return s.replace()
+	}
+	if err != nil {
+		return err
+	}
+	// the staging folder holds the previous target now
+	previous := s.dir
+	s.dir = ""
+	return os.RemoveAll(previous)
+}
+
+// replace moves the target aside and the staging folder in place, restoring the target when
+// the staging can't be moved.
+func (s *Staging) replace() error {
+	previous := s.dir + ".previous"
+	if err := os.Rename(s.target, previous); err != nil {
+		return err
+	}
+	if err := os.Rename(s.dir, s.target); err != nil {
+		if rerr := os.Rename(previous, s.target); rerr != nil {
+			return fmt.Errorf("%w, the previous target couldn't be restored from %s: %w", err, previous, rerr)
+		}
+		return err
+	}
+	s.dir = ""
+	return os.RemoveAll(previous)
+}
+
+// Discard removes the staging folder, the target is left untouched. It's a no-op once the
+// staging is committed.
+func (s *Staging) Discard() error {
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// linkTree recreates the folders and symbolic links of src into dst, hard linking its regular
+// files or copying them when they can't be linked (i.e. not supported by the filesystem).
+func linkTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			if err := os.Link(path, target); err == nil {
+				return nil
+			}
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+// copyFile copies the regular file src into dst, with the informed permissions.
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,17 @@
+package catalog
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// exchange atomically swaps the a and b paths, errExchangeUnsupported when the filesystem
+// doesn't support it.
+func exchange(a, b string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, a, unix.AT_FDCWD, b, unix.RENAME_EXCHANGE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) {
+		return errExchangeUnsupported
+	}
+	return err
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package catalog
+
+// exchange atomically swaps the a and b paths, not supported on this platform.
+func exchange(_, _ string) error {
+	return errExchangeUnsupported
+}
@@ -0,0 +1,111 @@
+package catalog_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestStaging(t *testing.T) {
+	dir := fs.NewDir(t, "staging", fs.WithDir("catalog",
+		fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))
+	defer dir.Remove()
+	target := filepath.Join(dir.Path(), "catalog")
+
+	s, err := catalog.NewStaging(target)
+	assert.NilError(t, err)
+	assert.Assert(t, fs.Equal(s.Dir(), fs.Expected(t, fs.WithMode(0o755|os.ModeDir),
+		fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task")))))
+
+	// discarded, the target is left untouched, the staged files are replaced not modified
+	assert.NilError(t, os.Remove(filepath.Join(s.Dir(), "tasks", "task.yaml")))
+	assert.NilError(t, os.WriteFile(filepath.Join(s.Dir(), "tasks", "task.yaml"), []byte("kind: Pipeline"), 0o644))
+	assert.NilError(t, s.Discard())
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t, fs.WithMode(0o700|os.ModeDir),
+		fs.WithDir("catalog", fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))))
+
+	// committed, the staging replaces the target
+	s, err = catalog.NewStaging(target)
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filepath.Join(s.Dir(), "README.md"), []byte("catalog"), 0o644))
+	assert.NilError(t, s.Commit())
+	assert.NilError(t, s.Discard())
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t, fs.WithMode(0o700|os.ModeDir),
+		fs.WithDir("catalog",
+			fs.WithFile("README.md", "catalog"),
+			fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))))
+}
+
+func TestStagingNewTarget(t *testing.T) {
+	dir := fs.NewDir(t, "staging")
+	defer dir.Remove()
+	target := filepath.Join(dir.Path(), "catalog")
+
+	s, err := catalog.NewStaging(target)
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filepath.Join(s.Dir(), "README.md"), []byte("catalog"), 0o644))
+	assert.NilError(t, s.Commit())
+	payload, err := os.ReadFile(filepath.Join(target, "README.md"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(payload), "catalog")
+}
+
+func TestStagingLinks(t *testing.T) {
+	dir := fs.NewDir(t, "staging", fs.WithDir("catalog",
+		fs.WithFile(catalog.ManifestFilename, "entries: [] # previous\n"),
+		fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))
+	defer dir.Remove()
+	target := filepath.Join(dir.Path(), "catalog")
+
+	// the target files are linked, not copied
+	s, err := catalog.NewStaging(target)
+	assert.NilError(t, err)
+	defer s.Discard() //nolint:errcheck
+	original, err := os.Stat(filepath.Join(target, "tasks", "task.yaml"))
+	assert.NilError(t, err)
+	staged, err := os.Stat(filepath.Join(s.Dir(), "tasks", "task.yaml"))
+	assert.NilError(t, err)
+	assert.Assert(t, os.SameFile(original, staged))
+
+	// generating the catalog on the staging leaves the target untouched
+	c := catalog.Catalog{Repositories: map[string]catalog.Repository{}}
+	_, err = catalog.GenerateFilesystem(context.Background(), s.Dir(), c, "tasks", catalog.Options{})
+	assert.NilError(t, err)
+	assert.Assert(t, fs.Equal(target, fs.Expected(t, fs.WithMode(0o755|os.ModeDir),
+		fs.WithFile(catalog.ManifestFilename, "entries: [] # previous\n"),
+		fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task")))))
+
+	// the previous target is gone once swapped
+	assert.NilError(t, s.Commit())
+	entries, err := os.ReadDir(dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Name(), "catalog")
+}
+
+func TestStagingCurrentDirectory(t *testing.T) {
+	dir := fs.NewDir(t, "staging", fs.WithDir("catalog",
+		fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))
+	defer dir.Remove()
+	wd, err := os.Getwd()
+	assert.NilError(t, err)
+	assert.NilError(t, os.Chdir(dir.Join("catalog")))
+	t.Cleanup(func() { assert.NilError(t, os.Chdir(wd)) })
+
+	// the staging is created next to the current directory, not inside it
+	s, err := catalog.NewStaging(".")
+	assert.NilError(t, err)
+	assert.Equal(t, filepath.Dir(s.Dir()), dir.Path())
+	assert.NilError(t, os.WriteFile(filepath.Join(s.Dir(), "README.md"), []byte("catalog"), 0o644))
+	assert.NilError(t, s.Commit())
+	assert.NilError(t, os.Chdir(wd))
+	assert.Assert(t, fs.Equal(dir.Path(), fs.Expected(t, fs.WithMode(0o700|os.ModeDir),
+		fs.WithDir("catalog",
+			fs.WithFile("README.md", "catalog"),
+			fs.WithDir("tasks", fs.WithFile("task.yaml", "kind: Task"))))))
+}
@@ -20,7 +20,9 @@ func CatalogCmd(cfg *config.Config) *cobra.Command {
 
 	catalogCmd.AddCommand(NewCatalogGenerateCmd(cfg))
 	catalogCmd.AddCommand(NewCatalogGenerateFromExternalCmd(cfg))
+	catalogCmd.AddCommand(NewCatalogLockCmd(cfg))
 	catalogCmd.AddCommand(NewCatalogExternalsCmd(cfg))
+	catalogCmd.AddCommand(NewCatalogPolicyCmd(cfg))
 
 	return catalogCmd
 }
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
 	"github.com/spf13/cobra"
 )
@@ -28,10 +29,15 @@ Generate a GitHub matrix strategy-compatible json from an externals.yaml file.
 type GitHubRunObject struct {
 	Name                 string `json:"name"`
 	URL                  string `json:"url"`
+	Provider             string `json:"provider,omitempty"`
 	Type                 string `json:"type"`
 	IgnoreVersions       string `json:"ignoreVersions"`
+	Versions             string `json:"versions,omitempty"`
 	CatalogName          string `json:"catalog-name"`
 	ResourcesTarballName string `json:"resources-tarball-name"`
+	MaxReleases          int    `json:"max-releases,omitempty"`
+	Since                string `json:"since,omitempty"`
+	Channel              string `json:"channel,omitempty"`
 }
 
 type GitHubMatrixObject struct {
@@ -62,7 +68,7 @@ func runCatalogExternals(_ context.Context, cfg *config.Config, args []string, o
 	for _, repository := range e.Repositories {
 		types := repository.Types
 		if len(types) == 0 {
-			types = []string{"tasks", "pipelines"}
+			types = contract.ResourceTypes
 		}
 		ignoreVersions := ""
 		if len(repository.IgnoreVersions) > 0 {
@@ -76,10 +82,15 @@ func runCatalogExternals(_ context.Context, cfg *config.Config, args []string, o
 			o := GitHubRunObject{
 				Name:                 name,
 				URL:                  repository.URL,
+				Provider:             repository.Provider,
 				Type:                 t,
 				IgnoreVersions:       ignoreVersions,
+				Versions:             repository.Versions,
 				CatalogName:          repository.CatalogName,
 				ResourcesTarballName: repository.ResourcesTarballName,
+				MaxReleases:          repository.MaxReleases,
+				Since:                repository.Since,
+				Channel:              repository.Channel,
 			}
 			m.Include = append(m.Include, o)
 		}
@@ -6,7 +6,6 @@ import (
 	"path"
 	"strings"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
@@ -16,13 +15,21 @@ import (
 
 // generateFromExternalOptions represents the "generate" subcommand to generate the signature of a resource file.
 type generateFromExternalOptions struct {
+	fetchOptions
+
 	name                string // name of the repository to pull (a bit useless)
 	url                 string // url of the repository to pull
+	provider            string // provider hosting the repository (github, gitlab, gitea, forgejo, oci)
 	resourceType        string // type of resource to pull
 	ignoreVersions      string // versions to ignore while pulling
+	versions            string // semantic version constraint the versions pulled must satisfy
 	target              string // path to the folder where we want to generate the catalog
 	catalogName         string // name of the contract file to pull (default catalog.yaml)
 	resourceTarballName string // name of the resources file to pull (default resources.tar.gz)
+	maxReleases         int    // maximum amount of most recent releases to pull
+	since               string // ignore releases published before this date
+	channel             string // release channel (stable, prerelease or all)
+	publicKey           string // trusted public key verifying the resources signatures
 }
 
 const generateLongFromExternalDescription = `# catalog-cd generate-partial
@@ -35,23 +42,31 @@ Generates a partial file-based catalog in the target folder, based of a set of f
       /path/to/catalog/target
 `
 
-func runGenerateFromExternal(_ context.Context, cfg *config.Config, args []string, o generateFromExternalOptions) error {
+func runGenerateFromExternal(ctx context.Context, cfg *config.Config, args []string, o generateFromExternalOptions) error {
 	if o.url == "" {
 		return fmt.Errorf("flag --config is required")
 	}
 	if o.resourceType == "" {
 		return fmt.Errorf("flag --resourceType is required")
 	}
+	if err := contract.ValidateResourceType(o.resourceType); err != nil {
+		return err
+	}
+
+	if err := fc.ValidateChannel(o.channel); err != nil {
+		return err
+	}
 
 	if len(args) != 1 {
 		return fmt.Errorf("you must specify a target to generate the catalog in")
 	}
 	o.target = args[0]
 	cfg.Infof("Generating a partial catalog from %s (type: %s)\n", o.url, o.resourceType)
-	ghclient, err := api.DefaultRESTClient()
+	transport, err := o.setup()
 	if err != nil {
 		return err
 	}
+	defer o.report(cfg)
 
 	name := o.name
 	if name == "" {
@@ -66,17 +81,35 @@ func runGenerateFromExternal(_ context.Context, cfg *config.Config, args []strin
 		Repositories: []fc.Repository{{
 			Name:                 name,
 			URL:                  o.url,
+			Provider:             o.provider,
 			IgnoreVersions:       ignoreVersions,
+			Versions:             o.versions,
 			CatalogName:          o.catalogName,
 			ResourcesTarballName: o.resourceTarballName,
+			MaxReleases:          o.maxReleases,
+			Since:                o.since,
+			Channel:              o.channel,
+			PublicKey:            o.publicKey,
 		}},
 	}
-	c, err := catalog.FetchFromExternals(e, ghclient)
+	opts := o.catalogOptions(e.Hosts, transport)
+	c, err := catalog.FetchFromExternals(ctx, e, opts)
 	if err != nil {
 		return err
 	}
 
-	return catalog.GenerateFilesystem(o.target, c, o.resourceType)
+	// the catalog is generated on a staging copy of the target, swapped in place on success
+	staging, err := catalog.NewStaging(o.target)
+	if err != nil {
+		return err
+	}
+	defer staging.Discard() //nolint:errcheck
+	report, err := catalog.GenerateFilesystem(ctx, staging.Dir(), c, o.resourceType, opts)
+	if err != nil {
+		return err
+	}
+	printReport(cfg, o.target, report)
+	return staging.Commit()
 }
 
 // NewCatalogGenerateFromExternalCmd instantiates the "generate" subcommand.
@@ -95,10 +128,18 @@ func NewCatalogGenerateFromExternalCmd(cfg *config.Config) *cobra.Command {
 
 	cmd.PersistentFlags().StringVar(&o.name, "name", "", "name of the repository to pull")
 	cmd.PersistentFlags().StringVar(&o.url, "url", "", "url of the repository to pull")
-	cmd.PersistentFlags().StringVar(&o.resourceType, "type", "", "type of resource to pull")
+	cmd.PersistentFlags().StringVar(&o.provider, "provider", "", "provider hosting the repository (github, gitlab, gitea, forgejo, oci), inferred for the oci and public hosts url by default")
+	cmd.PersistentFlags().StringVar(&o.resourceType, "type", "", "type of resource to pull (tasks, pipelines or stepactions)")
 	cmd.PersistentFlags().StringVar(&o.ignoreVersions, "ignore-versions", "", "versions to ignore while pulling")
+	cmd.PersistentFlags().StringVar(&o.versions, "versions", "", "semantic version constraint the versions pulled must satisfy (e.g. \">=0.3.0 <2.0.0\", \"~1.4\")")
 	cmd.PersistentFlags().StringVar(&o.catalogName, "catalog-name", contract.Filename, "contract name to pull")
 	cmd.PersistentFlags().StringVar(&o.resourceTarballName, "resource-tarball-name", contract.ResourcesName, "resource file to pull")
+	cmd.PersistentFlags().IntVar(&o.maxReleases, "max-releases", 0, "maximum amount of most recent releases to pull, unlimited by default")
+	cmd.PersistentFlags().StringVar(&o.channel, "channel", fc.ChannelStable, "release channel (stable, prerelease or all)")
+	cmd.PersistentFlags().StringVar(&o.publicKey, "public-key", "", "trusted public key file verifying the resources signatures, unsigned resources are rejected")
+	cmd.PersistentFlags().StringVar(&o.since, "since", "", "ignore releases published before this date (2006-01-02 or RFC3339)")
+
+	o.addFlags(cmd.PersistentFlags())
 
 	return cmd
 }
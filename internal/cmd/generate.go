@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
@@ -14,23 +13,61 @@ import (
 
 // generateOptions represents the "generate" subcommand to generate the signature of a resource file.
 type generateOptions struct {
-	config string // path for the catalog configuration file
-	target string // path to the folder where we want to generate the catalog
+	fetchOptions
+
+	config   string // path for the catalog configuration file
+	target   string // path to the folder where we want to generate the catalog
+	channel  string // release channel, overrides the repositories configuration
+	frozen   bool   // fails when the upstream content differs from the lock file
+	lockFile string // path for the lock file
+	prune    bool   // removes the versions no longer part of the catalog
+	dryRun   bool   // only lists the versions pruned
 }
 
 const generateLongDescription = `# catalog-cd generate
 
 Generates a file-based catalog in the target folder, based of a configuration file.
 
+The generation is incremental, versions already present in the target folder whose resources
+match the contract checksums are left untouched. The versions added, updated or left untouched
+are reported at the end.
+
+The catalog is generated on a staging copy of the target folder, swapped in place once the
+generation succeeded. Versions which couldn't be fetched are skipped, with "--strict" all the
+failures are reported and the target folder is left untouched.
+
+Releases, contracts and tarballs are kept on a local http cache ("--cache-dir") revalidated on
+each run, "--offline" generates the catalog only from the cache contents.
+
+Resources coming from pre-releases or drafts, fetched using the "prerelease" or "all"
+channel, are annotated with "tekton.dev/channel".
+
+With "--prune" the versions which are no longer part of the catalog (ignored, deleted upstream,
+…) are removed from the target folder, only the versions generated by catalog-cd are considered
+as tracked by the manifest file in the target folder. Use "--dry-run" to only list them.
+
+With "--frozen" the releases must match the lock file written by "catalog lock", the generation
+fails when a release, a contract or a resources tarball differs from the lock.
+
+Repositories pinning a trusted "public-key" (key file location or inline PEM) in the
+configuration must sign every resource, the signatures are verified before writing the
+resources and the versions with unsigned or badly signed resources are rejected. With
+"require-signatures" the contract and the resources tarball must carry a detached signature
+release asset ("catalog.yaml.sig" and "resources.tar.gz.sig"), and the tarball must match the
+checksum recorded on the contract.
+
   $ catalog-cd generate \
       --config="/path/to/external.yaml" \
       /path/to/catalog/target
 `
 
-func runGenerate(_ context.Context, cfg *config.Config, args []string, o generateOptions) error {
+func runGenerate(ctx context.Context, cfg *config.Config, args []string, o generateOptions) error {
 	if o.config == "" {
 		return fmt.Errorf("flag --config is required")
 	}
+	if o.dryRun && !o.prune {
+		return fmt.Errorf("flag --dry-run requires --prune")
+	}
 
 	if len(args) != 1 {
 		return fmt.Errorf("you must specify a target to generate the catalog in")
@@ -45,21 +82,83 @@ func runGenerate(_ context.Context, cfg *config.Config, args []string, o generat
 		}
 	}
 	cfg.Infof("Generating a catalog from %s in %s\n", o.config, o.target)
-	ghclient, err := api.DefaultRESTClient()
+	transport, err := o.setup()
 	if err != nil {
 		return err
 	}
+	defer o.report(cfg)
 
 	e, err := fc.LoadExternal(o.config)
 	if err != nil {
 		return err
 	}
-	c, err := catalog.FetchFromExternals(e, ghclient)
+	if o.channel != "" {
+		if err := fc.ValidateChannel(o.channel); err != nil {
+			return err
+		}
+		for i := range e.Repositories {
+			e.Repositories[i].Channel = o.channel
+		}
+	}
+	var l *catalog.Lock
+	if o.frozen {
+		if o.lockFile == "" {
+			o.lockFile = catalog.LockFilename(o.config)
+		}
+		if l, err = catalog.LoadLock(o.lockFile); err != nil {
+			return err
+		}
+	}
+	opts := o.catalogOptions(e.Hosts, transport)
+	c, err := catalog.FetchFromExternals(ctx, e, opts)
 	if err != nil {
 		return err
 	}
+	if l != nil {
+		if err := l.Freeze(c); err != nil {
+			return err
+		}
+	}
 
-	return catalog.GenerateFilesystem(o.target, c, "")
+	if o.dryRun {
+		return runPrune(cfg, o.target, o.target, c, true)
+	}
+
+	// the catalog is generated on a staging copy of the target, swapped in place on success
+	staging, err := catalog.NewStaging(o.target)
+	if err != nil {
+		return err
+	}
+	defer staging.Discard() //nolint:errcheck
+	report, err := catalog.GenerateFilesystem(ctx, staging.Dir(), c, "", opts)
+	if err != nil {
+		return err
+	}
+	printReport(cfg, o.target, report)
+	if o.prune {
+		if err := runPrune(cfg, o.target, staging.Dir(), c, false); err != nil {
+			return err
+		}
+	}
+	return staging.Commit()
+}
+
+// runPrune prunes the versions no longer part of the catalog target generated on dir, on dry-run
+// they are only listed.
+func runPrune(cfg *config.Config, target, dir string, c catalog.Catalog, dryRun bool) error {
+	pruned, err := catalog.Prune(dir, c, "", dryRun)
+	if err != nil {
+		return err
+	}
+	verb := "Pruned"
+	if dryRun {
+		verb = "Would prune"
+	}
+	cfg.Infof("# %s %d versions from %s\n", verb, len(pruned), target)
+	for _, e := range pruned {
+		cfg.Infof("#   - %s\n", e)
+	}
+	return nil
 }
 
 // NewCatalogGenerateCmd instantiates the "generate" subcommand.
@@ -77,6 +176,13 @@ func NewCatalogGenerateCmd(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVar(&o.config, "config", "./externals.yaml", "path of the catalog configuration file")
+	cmd.PersistentFlags().StringVar(&o.channel, "channel", "", "release channel (stable, prerelease or all), overrides the configuration file")
+	cmd.PersistentFlags().BoolVar(&o.prune, "prune", false, "removes the versions no longer part of the catalog from the target")
+	cmd.PersistentFlags().BoolVar(&o.dryRun, "dry-run", false, "with --prune, only lists the versions which would be removed, without generating the catalog")
+	cmd.PersistentFlags().BoolVar(&o.frozen, "frozen", false, "fails when the upstream content differs from the lock file")
+	cmd.PersistentFlags().StringVar(&o.lockFile, "lock-file", "", "path of the lock file used by --frozen, next to the configuration file by default")
+
+	o.addFlags(cmd.PersistentFlags())
 
 	return cmd
 }
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/spf13/cobra"
+)
+
+// lockOptions represents the "lock" subcommand to record the releases of the external repositories.
+type lockOptions struct {
+	fetchOptions
+
+	config   string // path for the catalog configuration file
+	lockFile string // path for the lock file
+}
+
+const lockLongDescription = `# catalog-cd lock
+
+Resolves the releases of the external repositories, and records them on a lock file along the
+SHA256 digest of their contract and resources tarball. By default the lock file is stored next to
+the configuration file, "externals.yaml" is locked by "externals.lock".
+
+The lock file is then used by "catalog generate --frozen", which fails when the upstream content
+differs from the lock.
+
+  $ catalog-cd catalog lock --config="/path/to/externals.yaml"
+`
+
+func runLock(ctx context.Context, cfg *config.Config, o lockOptions) error {
+	if o.config == "" {
+		return fmt.Errorf("flag --config is required")
+	}
+	if o.lockFile == "" {
+		o.lockFile = catalog.LockFilename(o.config)
+	}
+	transport, err := o.setup()
+	if err != nil {
+		return err
+	}
+	defer o.report(cfg)
+
+	e, err := fc.LoadExternal(o.config)
+	if err != nil {
+		return err
+	}
+	opts := o.catalogOptions(e.Hosts, transport)
+	c, err := catalog.FetchFromExternals(ctx, e, opts)
+	if err != nil {
+		return err
+	}
+	l, err := catalog.NewLock(ctx, e, c, opts)
+	if err != nil {
+		return err
+	}
+	if err := l.Save(o.lockFile); err != nil {
+		return err
+	}
+	cfg.Infof("Locked %d repositories in %s\n", len(l.Repositories), o.lockFile)
+	return nil
+}
+
+// NewCatalogLockCmd instantiates the "lock" subcommand.
+func NewCatalogLockCmd(cfg *config.Config) *cobra.Command {
+	o := lockOptions{}
+	cmd := &cobra.Command{
+		Use:          "lock",
+		Args:         cobra.NoArgs,
+		Long:         lockLongDescription,
+		Short:        "Records the releases of the external repositories on a lock file.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runLock(cmd.Context(), cfg, o)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&o.config, "config", "./externals.yaml", "path of the catalog configuration file")
+	cmd.PersistentFlags().StringVar(&o.lockFile, "lock-file", "", "path of the lock file, next to the configuration file by default")
+
+	o.addFlags(cmd.PersistentFlags())
+
+	return cmd
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/spf13/cobra"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+)
+
+// policyOptions represents the "policy" subcommand to write the Tekton VerificationPolicy of
+// the catalog repositories.
+type policyOptions struct {
+	fetchOptions
+
+	config    string // path for the catalog configuration file
+	output    string // path of the policies file, standard output by default
+	namespace string // namespace of the policies
+	mode      string // policy mode, enforce or warn
+	pinned    bool   // only trusts the pinned public-keys
+}
+
+const policyLongDescription = `# catalog-cd catalog policy
+
+Writes a Tekton VerificationPolicy ("tekton.dev/v1alpha1") for each repository of the externals
+configuration, to enforce on the cluster the trust decisions made by the catalog. The resources
+are matched by the repository source URL (git, http or bundles resolvers), and the keys are
+embedded inline.
+
+The keys trusted are the "public-key" pinned by the externals configuration, or else the
+attestation keys of the repository contracts, with a warning as the contracts are not verified.
+The flag "--pinned-only" skips the repositories without a pinned "public-key" instead. Contract
+keys referencing files on the upstream repository can't be embedded and are skipped, KMS URIs
+and Kubernetes Secrets ("k8s://") are referenced as they are.
+
+When the target of a generated catalog is informed, only the repositories and releases of the
+generated catalog are considered.
+
+  $ catalog-cd catalog policy --config="/path/to/externals.yaml" --namespace="tekton-tasks"
+
+  $ catalog-cd catalog policy --config="/path/to/externals.yaml" \
+      --output="policies.yaml" /path/to/catalog/target
+`
+
+func runPolicy(ctx context.Context, cfg *config.Config, args []string, o policyOptions) error {
+	if o.config == "" {
+		return fmt.Errorf("flag --config is required")
+	}
+	transport, err := o.setup()
+	if err != nil {
+		return err
+	}
+	defer o.report(cfg)
+
+	e, err := fc.LoadExternal(o.config)
+	if err != nil {
+		return err
+	}
+	opts := o.catalogOptions(e.Hosts, transport)
+	c, err := catalog.FetchFromExternals(ctx, e, opts)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		if c, err = catalog.GeneratedCatalog(c, args[0]); err != nil {
+			return err
+		}
+	}
+	policies, err := catalog.Policies(cfg.Stream.Err, c, catalog.PolicyOptions{
+		Namespace:  o.namespace,
+		Mode:       v1alpha1.ModeType(o.mode),
+		PinnedOnly: o.pinned,
+	})
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = cfg.Stream.Out
+	if o.output != "" {
+		f, err := os.Create(o.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := catalog.WritePolicies(w, policies); err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.Stream.Err, "# Written %d verification policies\n", len(policies))
+	return nil
+}
+
+// NewCatalogPolicyCmd instantiates the "policy" subcommand.
+func NewCatalogPolicyCmd(cfg *config.Config) *cobra.Command {
+	o := policyOptions{}
+	cmd := &cobra.Command{
+		Use:          "policy [flags] [target]",
+		Args:         cobra.MaximumNArgs(1),
+		Long:         policyLongDescription,
+		Short:        "Writes the Tekton VerificationPolicy of the catalog repositories.",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPolicy(cmd.Context(), cfg, args, o)
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&o.config, "config", "./externals.yaml", "path of the catalog configuration file")
+	cmd.PersistentFlags().StringVar(&o.output, "output", "", "path of the policies file, standard output by default")
+	cmd.PersistentFlags().StringVar(&o.namespace, "namespace", "", "namespace of the policies")
+	cmd.PersistentFlags().StringVar(&o.mode, "mode", string(v1alpha1.ModeEnforce), "policy mode (enforce or warn)")
+	cmd.PersistentFlags().BoolVar(&o.pinned, "pinned-only", false, "only trusts the public-keys pinned by the externals configuration")
+
+	o.addFlags(cmd.PersistentFlags())
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -10,10 +11,15 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+	"github.com/openshift-pipelines/catalog-cd/internal/provenance"
 	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
 	"github.com/spf13/cobra"
 )
 
@@ -24,6 +30,10 @@ type releaseOptions struct {
 	output        string   // output path, where the contract and tarball will be written
 	catalogName   string   // name for the catalog.yaml
 	resourcesName string   // name for the resources tarball containing names
+	ociRef        string   // OCI reference to push the release to
+	provenance    bool     // emits the release provenance statement
+	builderID     string   // provenance builder identifier
+	privateKey    string   // private key embedding the resources signature
 }
 
 const releaseLongDescription = `# catalog-cd release
@@ -46,9 +56,32 @@ order to change its location see "--output" flag.
 
 It always require the "--version" flag specifying the common revision for all
 resources in scope.
+
+Files holding several YAML documents are split, each Tekton resource is released on its own
+file ("{kind}s/{name}/{name}.yaml") and recorded on the contract. Documents which aren't
+supported Tekton resources (a ConfigMap, for instance) are skipped with a warning.
+
+The release can also be pushed as a OCI artifact, containing the contract and the tarball,
+using "--oci-ref". When the reference has no tag, the release version is used.
+
+  $ catalog-cd release --version="0.0.1" --oci-ref="quay.io/org/tasks" *.yaml
+
+With "--provenance" the release emits an in-toto statement with a SLSA provenance predicate
+("provenance.intoto.json"), its subjects are the contract, the tarball and each resource
+digest. "catalog-cd sign" signs the statement into a DSSE envelope.
+
+  $ catalog-cd release --version="0.0.1" --provenance --builder-id="https://ci.example.com" *.yaml
+
+With "--private-key" the released resources are signed for Tekton Pipelines trusted resources,
+the signature is embedded on the "tekton.dev/signature" annotation of each resource, and the
+contract records the checksum of the signed resources. "catalog-cd verify" checks the embedded
+signatures.
+
+  $ catalog-cd release --version="0.0.1" --private-key="cosign.key" *.yaml
 `
 
-func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseOptions) error {
+func runRelease(ctx context.Context, cfg *config.Config, args []string, o releaseOptions) error {
+	startedOn := time.Now()
 	// making sure the output flag is informed before attempt to search files
 	if o.output == "" {
 		return fmt.Errorf("--output flag is not informed")
@@ -62,6 +95,8 @@ func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseO
 	// going through the pattern slice collected before to select the tekton resource files
 	// to be part of the current release, in other words, release scope
 	fmt.Fprintf(cfg.Stream.Err, "# Scan Tekton resources on: %s\n", strings.Join(o.paths, ", "))
+	// released files on the output and their source file
+	released := map[string]string{}
 	for _, p := range o.paths {
 		files, err := resource.Scanner(p)
 		if err != nil {
@@ -70,12 +105,25 @@ func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseO
 
 		for _, f := range files {
 			fmt.Fprintf(cfg.Stream.Err, "# Loading resource file: %q\n", f)
+			docs, err := resource.ReadDocuments(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f, err)
+			}
+			if len(docs) > 1 {
+				if err := releaseDocuments(cfg, c, o, f, docs, released); err != nil {
+					return err
+				}
+				continue
+			}
 			taskname := filepath.Base(filepath.Dir(f))
 			resourceType, err := resource.GetResourceType(f)
 			if err != nil {
 				return err
 			}
 			resourceFolder := filepath.Join(o.output, strings.ToLower(resourceType)+"s", taskname)
+			if err := claimReleaseFile(released, filepath.Join(resourceFolder, filepath.Base(f)), f); err != nil {
+				return err
+			}
 			if err := os.MkdirAll(resourceFolder, os.ModePerm); err != nil {
 				return err
 			}
@@ -100,16 +148,144 @@ func runRelease(_ context.Context, cfg *config.Config, args []string, o releaseO
 		}
 	}
 
+	// embedding the signatures modifies the resources, their checksum is recomputed
+	if o.privateKey != "" {
+		if err := embedSignatures(ctx, cfg, c, o); err != nil {
+			return err
+		}
+	}
+
+	// Create a tarball (without catalog.yaml
 	catalogPath := filepath.Join(o.output, o.catalogName)
+	tarball := filepath.Join(o.output, o.resourcesName)
+	fmt.Fprintf(cfg.Stream.Err, "# Creating tarball at %q\n", tarball)
+	if err := createTektonResourceArchive(tarball, o.catalogName, o.resourcesName, o.output); err != nil {
+		return err
+	}
+	// the contract records the tarball checksum, signing the contract covers the tarball, "sign"
+	// rebuilds the tarball with the resources signatures and records its checksum again
+	if err := c.SetTarball(tarball); err != nil {
+		return err
+	}
 	fmt.Fprintf(cfg.Stream.Err, "# Saving release contract at %q\n", catalogPath)
 	if err := c.SaveAs(catalogPath); err != nil {
 		return err
 	}
+	if o.provenance {
+		statement, err := provenance.New(c, catalogPath, tarball, provenance.Build{
+			BuilderID: o.builderID,
+			Parameters: provenance.Parameters{
+				Version: o.version,
+				Paths:   o.paths,
+				OCIRef:  o.ociRef,
+			},
+			StartedOn:  startedOn,
+			FinishedOn: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		provenanceFile := filepath.Join(o.output, provenance.Filename)
+		fmt.Fprintf(cfg.Stream.Err, "# Saving release provenance at %q\n", provenanceFile)
+		if err := provenance.Save(statement, provenanceFile); err != nil {
+			return err
+		}
+	}
 
-	// Create a tarball (without catalog.yaml
-	tarball := filepath.Join(o.output, o.resourcesName)
-	fmt.Fprintf(cfg.Stream.Err, "# Creating tarball at %q\n", tarball)
-	return createTektonResourceArchive(tarball, o.catalogName, o.resourcesName, o.output)
+	if o.ociRef == "" {
+		return nil
+	}
+	ref := ociReferenceWithTag(o.ociRef, o.version)
+	fmt.Fprintf(cfg.Stream.Err, "# Pushing release to %q\n", ref)
+	digest, err := oci.Push(ctx, ref, catalogPath, tarball)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.Stream.Err, "# Pushed %s@%s\n", ref, digest)
+	return nil
+}
+
+// releaseDocuments releases the Tekton resources of a multi-document file, each one on its own
+// file ("{kind}s/{name}/{name}.yaml"), so every contract entry still describes a single resource.
+// Documents which aren't supported Tekton resources are skipped with a warning, the README next
+// to the file follows the first resource released.
+func releaseDocuments(cfg *config.Config, c *contract.Contract, o releaseOptions, f string, docs []resource.Document, released map[string]string) error {
+	readmeFile := filepath.Join(filepath.Dir(f), "README.md")
+	for _, doc := range docs {
+		kind, name := doc.Object.GetKind(), doc.Object.GetName()
+		if err := contract.IsResourceSupported(doc.Object); err != nil {
+			fmt.Fprintf(cfg.Stream.Err, "# WARNING: Skipping %s %q of file %q: %s\n", kind, name, f, err)
+			continue
+		}
+		if name == "" {
+			return fmt.Errorf("%s: %s without name, a name is required on multi-document files", f, kind)
+		}
+		resourceFolder := filepath.Join(o.output, strings.ToLower(kind)+"s", name)
+		resourceFile := filepath.Join(resourceFolder, name+".yaml")
+		if err := claimReleaseFile(released, resourceFile, f); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.Stream.Err, "# Releasing %s %q of file %q\n", kind, name, f)
+		if err := os.MkdirAll(resourceFolder, os.ModePerm); err != nil {
+			return err
+		}
+		payload := doc.Payload
+		if !bytes.HasSuffix(payload, []byte("\n")) {
+			payload = append(payload, '\n')
+		}
+		if err := os.WriteFile(resourceFile, payload, 0o644); err != nil { // nolint: gosec
+			return err
+		}
+		if err := c.AddResourceFile(resourceFile, o.version); err != nil {
+			return err
+		}
+		if readmeFile == "" {
+			continue
+		}
+		if _, err := os.Stat(readmeFile); err == nil {
+			if err := copyFile(readmeFile, filepath.Join(resourceFolder, "README.md")); err != nil {
+				return err
+			}
+		}
+		readmeFile = ""
+	}
+	return nil
+}
+
+// claimReleaseFile records the released file, two sources can't be released on the same file.
+func claimReleaseFile(released map[string]string, file, source string) error {
+	if previous, ok := released[file]; ok {
+		return fmt.Errorf("%s: resource file %q is already released from %s", source, file, previous)
+	}
+	released[file] = source
+	return nil
+}
+
+// embedSignatures signs the released resources on the output directory, for Tekton Pipelines
+// trusted resources, and updates the contract checksums.
+func embedSignatures(ctx context.Context, cfg *config.Config, c *contract.Contract, o releaseOptions) error {
+	helper, err := attestation.NewAttestation(o.privateKey)
+	if err != nil {
+		return err
+	}
+	defer helper.Close()
+	for _, r := range c.Catalog.Resources.All() {
+		file := filepath.Join(o.output, r.Filename)
+		fmt.Fprintf(cfg.Stream.Err, "# Embedding signature on resource %q...\n", file)
+		if err := trustedresources.Sign(ctx, helper, file); err != nil {
+			return err
+		}
+	}
+	return c.UpdateChecksums(o.output)
+}
+
+// ociReferenceWithTag appends the version as tag when the reference has neither tag nor digest.
+func ociReferenceWithTag(reference, version string) string {
+	ref := oci.TrimScheme(reference)
+	if strings.Contains(ref, "@") || strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") {
+		return ref
+	}
+	return fmt.Sprintf("%s:%s", ref, version)
 }
 
 // NewReleaseCmd instantiates the NewReleaseCmd subcommand and flags.
@@ -130,6 +306,10 @@ func NewReleaseCmd(cfg *config.Config) *cobra.Command {
 	cmd.PersistentFlags().StringVar(&o.output, "output", ".", "path to the release files (to attach to a given release)")
 	cmd.PersistentFlags().StringVar(&o.catalogName, "catalog-name", contract.Filename, "name for the catalog.yaml file")
 	cmd.PersistentFlags().StringVar(&o.resourcesName, "resources-tarball-name", contract.ResourcesName, "name for the catalog.yaml file")
+	cmd.PersistentFlags().StringVar(&o.ociRef, "oci-ref", "", "OCI reference (registry/repository[:tag]) to push the release to")
+	cmd.PersistentFlags().BoolVar(&o.provenance, "provenance", false, "emits the release provenance, an in-toto statement with a SLSA predicate")
+	cmd.PersistentFlags().StringVar(&o.builderID, "builder-id", provenance.DefaultBuilderID, "provenance builder identifier")
+	cmd.PersistentFlags().StringVar(&o.privateKey, "private-key", "", "private key file location, embeds the Tekton trusted resources signature on each resource")
 
 	if err := cmd.MarkPersistentFlagRequired("version"); err != nil {
 		panic(err)
@@ -166,7 +346,10 @@ func createArchive(output, catalogFileName, resourcesFileName string, buf io.Wri
 		if err != nil {
 			return err
 		}
-		if filepath.Base(file) == catalogFileName || filepath.Base(file) == resourcesFileName {
+		switch filepath.Base(file) {
+		case catalogFileName, resourcesFileName,
+			contract.SignatureFile(catalogFileName), contract.SignatureFile(resourcesFileName),
+			provenance.Filename, provenance.EnvelopeFilename:
 			return nil
 		}
 		if fi.IsDir() || !fi.Mode().IsRegular() {
@@ -13,9 +13,10 @@ import (
 const renderLongDescription = `# catalog-cd render
 
 Renders the informed Tekton resource file as markdown, focusing on the most important attributes
-which should always be part of the Task documentation.
+which should always be part of the Task, Pipeline or StepAction documentation.
 
 The markdown generated contains the Workspaces, Params and Results formated as a mardown tables.
+StepActions have no Workspaces, their Results are documented with the result type.
 `
 
 func runRender(_ context.Context, cfg *config.Config, args []string) error {
@@ -3,10 +3,13 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/provenance"
 	"github.com/spf13/cobra"
 )
 
@@ -15,6 +18,7 @@ type signOptions struct {
 	c *contract.Contract // catalog contract instance
 
 	privateKey string // private key location
+	keyID      string // identifier of the signing key, on the contract attestation keys
 }
 
 const signLongDescription = `# catalog-cd sign
@@ -22,11 +26,24 @@ const signLongDescription = `# catalog-cd sign
 Sign the catalog contract resources on the informed directory, or catalog file. By default it
 assumes the current directory.
 
+The resources are located relative to the contract, their detached signatures are written next
+to them ("tasks/{name}/{name}.yaml.sig") and recorded on the contract. The resources tarball
+next to the contract is rebuilt to carry the signatures, and its checksum recorded on the
+contract. The contract and the tarball are signed as well, the detached signatures are written
+next to them ("catalog.yaml.sig" and "resources.tar.gz.sig").
+
+When the contract lists several attestation keys (".catalog.attestation.publicKeys"), the
+"--key-id" flag records which of them signs the resources.
+
+The release provenance statement ("provenance.intoto.json"), when present next to the contract,
+is updated with the signed contract digest and signed into a DSSE envelope
+("provenance.intoto.jsonl").
+
 To sign the resources the subcommand requires a private-key ("--private-key" flag), and may
 ask for the password when trying to interact with a encripted key.
 `
 
-func runSign(_ context.Context, cfg *config.Config, args []string, o signOptions) error {
+func runSign(ctx context.Context, cfg *config.Config, args []string, o signOptions) error {
 	var err error
 	o.c, err = LoadContractFromArgs(args)
 	if err != nil {
@@ -36,13 +53,63 @@ func runSign(_ context.Context, cfg *config.Config, args []string, o signOptions
 	if err != nil {
 		return err
 	}
-	if err = o.c.SignResources(func(payladPath, outputSignature string) error {
+	dir := filepath.Dir(o.c.File())
+	if err = o.c.SignResources(o.keyID, func(payladPath, outputSignature string) error {
+		payladPath, outputSignature = filepath.Join(dir, payladPath), filepath.Join(dir, outputSignature)
 		fmt.Fprintf(cfg.Stream.Err, "# Signing resource %q on %q...\n", payladPath, outputSignature)
 		return helper.Sign(payladPath, outputSignature)
 	}); err != nil {
 		return err
 	}
-	return o.c.Save()
+
+	// the tarball is rebuilt with the resources signatures, its checksum recorded on the contract
+	// and signed as well
+	tarball := o.c.TarballFile()
+	if _, err := os.Stat(tarball); err == nil {
+		fmt.Fprintf(cfg.Stream.Err, "# Rebuilding tarball %q with the signatures...\n", tarball)
+		if err := createTektonResourceArchive(tarball, filepath.Base(o.c.File()), filepath.Base(tarball), dir); err != nil {
+			return err
+		}
+		if err := o.c.SetTarball(tarball); err != nil {
+			return err
+		}
+		fmt.Fprintf(cfg.Stream.Err, "# Signing tarball %q on %q...\n", tarball, contract.SignatureFile(tarball))
+		if err := helper.Sign(tarball, contract.SignatureFile(tarball)); err != nil {
+			return err
+		}
+	}
+	if err := o.c.Save(); err != nil {
+		return err
+	}
+	// the contract is signed once saved with the resources signatures
+	fmt.Fprintf(cfg.Stream.Err, "# Signing contract %q on %q...\n", o.c.File(), contract.SignatureFile(o.c.File()))
+	if err := helper.Sign(o.c.File(), contract.SignatureFile(o.c.File())); err != nil {
+		return err
+	}
+
+	// the provenance subjects are updated with the contract and tarball, as signed
+	statementFile := filepath.Join(dir, provenance.Filename)
+	if _, err := os.Stat(statementFile); err != nil {
+		return nil
+	}
+	statement, err := provenance.Load(statementFile)
+	if err != nil {
+		return err
+	}
+	for _, file := range []string{o.c.File(), tarball} {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := provenance.SetSubject(statement, file); err != nil {
+			return err
+		}
+	}
+	if err := provenance.Save(statement, statementFile); err != nil {
+		return err
+	}
+	envelopeFile := filepath.Join(dir, provenance.EnvelopeFilename)
+	fmt.Fprintf(cfg.Stream.Err, "# Signing provenance %q on %q...\n", statementFile, envelopeFile)
+	return provenance.Sign(ctx, helper, statement, envelopeFile)
 }
 
 // NewSignCmd instantiate the SignCmd and flags.
@@ -60,6 +127,7 @@ func NewSignCmd(cfg *config.Config) *cobra.Command {
 	}
 
 	cmd.PersistentFlags().StringVar(&o.privateKey, "private-key", "", "private key file location")
+	cmd.PersistentFlags().StringVar(&o.keyID, "key-id", "", "identifier of the signing key on the contract attestation keys, recorded on each resource")
 
 	if err := cmd.MarkPersistentFlagRequired("private-key"); err != nil {
 		panic(err)
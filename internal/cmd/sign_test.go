@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	tkncli "github.com/tektoncd/cli/pkg/cli"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const signTask = `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+spec:
+  steps:
+    - name: foo
+      image: registry.access.redhat.com/ubi9/ubi-minimal
+      script: echo foo
+`
+
+// TestReleaseSignGenerate releases and signs the resources, the catalog generated with the
+// pinned public key verifies the signatures carried by the tarball.
+func TestReleaseSignGenerate(t *testing.T) {
+	t.Setenv("COSIGN_PASSWORD", "secret")
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("secret"), nil })
+	assert.NilError(t, err)
+	keysDir := fs.NewDir(t, "keys",
+		fs.WithFile("cosign.key", string(keys.PrivateBytes)),
+		fs.WithFile("cosign.pub", string(keys.PublicBytes)))
+	defer keysDir.Remove()
+	src := fs.NewDir(t, "src", fs.WithDir("foo", fs.WithFile("foo.yaml", signTask)))
+	defer src.Remove()
+	out := fs.NewDir(t, "release")
+	defer out.Remove()
+	target := fs.NewDir(t, "catalog")
+	defer target.Remove()
+
+	ctx := context.Background()
+	cfg := &config.Config{Stream: &tkncli.Stream{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}}
+	assert.NilError(t, runRelease(ctx, cfg, []string{src.Join("foo")}, releaseOptions{
+		version:       "0.1.0",
+		output:        out.Path(),
+		catalogName:   contract.Filename,
+		resourcesName: contract.ResourcesName,
+	}))
+	// the tarball recorded by the release is replaced, sign owns the final tarball
+	assert.NilError(t, os.WriteFile(out.Join(contract.ResourcesName), []byte("rebuilt"), 0o644))
+	assert.NilError(t, runSign(ctx, cfg, []string{out.Path()}, signOptions{privateKey: keysDir.Join("cosign.key")}))
+
+	c, err := contract.NewContractFromFile(out.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(c.Catalog.Resources.Tasks), 1)
+	assert.Equal(t, c.Catalog.Resources.Tasks[0].Signature, "tasks/foo/foo.yaml.sig")
+	assert.NilError(t, c.VerifyTarball(c.TarballFile()))
+	_, err = os.Stat(contract.SignatureFile(c.TarballFile()))
+	assert.NilError(t, err)
+
+	server := httptest.NewServer(http.FileServer(http.Dir(out.Path())))
+	defer server.Close()
+	release := catalog.Release{
+		ResourcesURI:          server.URL + "/" + contract.ResourcesName,
+		Catalog:               c.Catalog,
+		PublicKey:             keysDir.Join("cosign.pub"),
+		RequireSignatures:     true,
+		ResourcesSignatureURI: server.URL + "/" + contract.SignatureFile(contract.ResourcesName),
+	}
+	report, err := catalog.GenerateFilesystem(ctx, target.Path(), catalog.Catalog{
+		Repositories: map[string]catalog.Repository{"foo": {"0.1.0": release}},
+	}, "", catalog.Options{Strict: true})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, report.Added, []string{"foo@0.1.0"})
+	_, err = os.Stat(target.Join("tasks", "foo", "0.1.0", "foo.yaml"))
+	assert.NilError(t, err)
+}
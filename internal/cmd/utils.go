@@ -1,6 +1,20 @@
 package cmd
 
-import "github.com/openshift-pipelines/catalog-cd/internal/contract"
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/cache"
+	"github.com/openshift-pipelines/catalog-cd/internal/catalog"
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/retry"
+	"github.com/spf13/pflag"
+)
 
 func LoadContractFromArgs(args []string) (*contract.Contract, error) {
 	var location string
@@ -11,3 +25,100 @@ func LoadContractFromArgs(args []string) (*contract.Contract, error) {
 	}
 	return contract.NewContractFromFile(location)
 }
+
+// fetchOptions common options to fetch resources from external repositories.
+type fetchOptions struct {
+	cacheDir string // cache location
+	noCache  bool   // disables the cache
+	offline  bool   // only uses the cache contents
+
+	parallelism int           // amount of concurrent requests
+	timeout     time.Duration // timeout of each request
+	strict      bool          // reports every failure, and fails
+
+	cache *cache.Cache     // cache instance, when enabled
+	retry *retry.Transport // retrying transport, reaching the network
+}
+
+// addFlags registers the fetch flags on the informed flag-set.
+func (o *fetchOptions) addFlags(flags *pflag.FlagSet) {
+	o.addCacheFlags(flags)
+	flags.IntVar(&o.parallelism, "parallelism", 4, "amount of concurrent requests")
+	flags.BoolVar(&o.strict, "strict", false, "reports every failure across repositories and versions, and fails instead of skipping them")
+	flags.DurationVar(&o.timeout, "timeout", 2*time.Minute, "timeout of each request, listing releases or downloading a contract or tarball")
+}
+
+// addCacheFlags registers the http cache flags on the informed flag-set, for commands
+// fetching a single release.
+func (o *fetchOptions) addCacheFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&o.cacheDir, "cache-dir", cache.DefaultDir(), "path to the http cache directory")
+	flags.BoolVar(&o.noCache, "no-cache", false, "disables the http cache")
+	flags.BoolVar(&o.offline, "offline", false, "only uses the http cache contents, without reaching the network")
+}
+
+// catalogOptions returns the options to fetch and generate the catalog, the clients are
+// reaching the informed hosts using the transport prepared by setup.
+func (o *fetchOptions) catalogOptions(hosts []fc.Host, transport http.RoundTripper) catalog.Options {
+	return catalog.Options{
+		Parallelism: o.parallelism,
+		Timeout:     o.timeout,
+		Strict:      o.strict,
+		Clients:     fetcher.NewClients(hosts, transport),
+	}
+}
+
+// setup prepares the transport to reach external repositories, with retries and caching.
+func (o *fetchOptions) setup() (http.RoundTripper, error) {
+	if o.parallelism < 1 {
+		return nil, fmt.Errorf("invalid --parallelism %d, expects at least one", o.parallelism)
+	}
+	o.retry = retry.New(nil)
+	var transport http.RoundTripper = o.retry
+	if o.noCache {
+		if o.offline {
+			return nil, fmt.Errorf("flags --offline and --no-cache are mutually exclusive")
+		}
+	} else {
+		var err error
+		o.cache, err = cache.New(o.cacheDir, o.offline)
+		if err != nil {
+			return nil, err
+		}
+		transport = o.cache.WithTransport(o.retry)
+	}
+	return transport, nil
+}
+
+// report prints the cache usage statistics, and the rate-limit remaining on each host.
+func (o *fetchOptions) report(cfg *config.Config) {
+	if o.cache != nil {
+		fmt.Fprintf(cfg.Stream.Err, "# HTTP cache (%s): %s\n", o.cacheDir, o.cache.Stats())
+	}
+	if o.retry == nil {
+		return
+	}
+	if n := o.retry.Retried(); n > 0 {
+		fmt.Fprintf(cfg.Stream.Err, "# Retried requests: %d\n", n)
+	}
+	for _, r := range o.retry.RateLimits() {
+		fmt.Fprintf(cfg.Stream.Err, "# Rate limit on %s\n", r)
+	}
+}
+
+// printReport prints the catalog generation report, listing the versions added, updated or
+// which failed.
+func printReport(cfg *config.Config, target string, report catalog.Report) {
+	cfg.Infof("# Catalog generated in %s: %s\n", target, report)
+	for _, l := range []struct {
+		title    string
+		versions []string
+	}{
+		{"added", report.Added},
+		{"updated", report.Updated},
+		{"failed", report.Failed},
+	} {
+		if len(l.versions) > 0 {
+			cfg.Infof("#   %s: %s\n", l.title, strings.Join(l.versions, ", "))
+		}
+	}
+}
@@ -2,19 +2,35 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/audit"
 	"github.com/openshift-pipelines/catalog-cd/internal/config"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	fc "github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/provenance"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
 	"github.com/spf13/cobra"
 )
 
 // verifyOptions represents the "verify" options to verify the signature of a resource file.
 type verifyOptions struct {
-	c         *contract.Contract
-	publicKey string // path to the public key file
+	fetchOptions
+
+	c                        *contract.Contract
+	publicKey                string // path to the public key file
+	at                       string // release time, selecting the attestation keys valid at that time
+	repository               string // url of the repository hosting the remote release
+	version                  string // version of the remote release
+	output                   string // remote release report format, table or json
+	requireContractSignature bool   // the contract detached signature must be present
 }
 
 const verifyLongDescription = `# catalog-cd verify
@@ -24,31 +40,179 @@ either a contract file as argument, or a directory containing the contract using
 name. By default it searches the current directory.
 
 In order to verify the signature the public-key is required, it's specified either on the
-catalog contract, or using the flag "--public-key".
+catalog contract, or using the flag "--public-key". The contract may list several keys
+(".catalog.attestation.publicKeys"), each resource is verified with the key recorded by its
+"keyID", or else with any of the keys valid when the release was published ("--at"). The
+annotations listed by the contract attestation must be present on every resource. Resources
+signed for Tekton Pipelines trusted resources ("tekton.dev/signature" annotation) have the
+embedded signature verified, the detached signature is then optional.
+
+The contract detached signature ("catalog.yaml.sig") is verified as well when present, it's
+required with the flag "--require-contract-signature". The resources tarball recorded on the
+contract must match its checksum, and its detached signature ("resources.tar.gz.sig") when
+present. The signed release provenance ("provenance.intoto.jsonl"), when
+present next to the contract, is verified offline: the DSSE envelope signature and every
+subject digest against the released files.
+
+A remote release is verified using either the repository URL and the release version, or the
+contract URL as argument. The contract and the tarball are downloaded, every resource checksum
+and signature is checked in memory, and a report lists every check ("--output" table or json),
+the command fails when one of them failed. The detached signatures the release doesn't publish
+are skipped, unless "--require-contract-signature" for the contract one. It's meant to audit an upstream release before
+adding it to the externals configuration.
+
+  $ catalog-cd verify \
+      --repository="https://github.com/openshift-pipelines/task-containers" \
+      --version="0.3.0" --public-key="cosign.pub"
+
+  $ catalog-cd verify --output=json \
+      https://github.com/openshift-pipelines/task-containers/releases/download/v0.3.0/catalog.yaml
 `
 
+// isRemoteContract asserts the argument is the location of a remote contract.
+func isRemoteContract(args []string) bool {
+	return len(args) == 1 && (strings.HasPrefix(args[0], "https://") || strings.HasPrefix(args[0], "http://"))
+}
+
+// runVerifyRemote verifies the remote release and prints the report of every check.
+func runVerifyRemote(ctx context.Context, cfg *config.Config, args []string, o verifyOptions) error {
+	if o.output != "table" && o.output != "json" {
+		return fmt.Errorf("invalid --output %q, expects table or json", o.output)
+	}
+	opts := audit.Options{PublicKey: o.publicKey, RequireContractSignature: o.requireContractSignature}
+	if o.at != "" {
+		var err error
+		if opts.At, err = time.Parse(time.RFC3339, o.at); err != nil {
+			return fmt.Errorf("invalid --at %q, expects a RFC3339 timestamp: %w", o.at, err)
+		}
+	}
+	transport, err := o.setup()
+	if err != nil {
+		return err
+	}
+	defer o.report(cfg)
+	opts.Clients = fetcher.NewClients(nil, transport)
+
+	var release audit.Release
+	switch {
+	case o.repository != "" && len(args) > 0:
+		return fmt.Errorf("flag --repository and the contract argument are mutually exclusive")
+	case o.repository != "":
+		if o.version == "" {
+			return fmt.Errorf("flag --version is required with --repository")
+		}
+		release, err = audit.ReleaseFromRepository(ctx, fc.Repository{URL: o.repository}, opts.Clients, o.version)
+	default:
+		release, err = audit.ReleaseFromContractURL(args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := audit.Verify(ctx, release, opts)
+	if err != nil {
+		return err
+	}
+	if o.output == "json" {
+		err = report.WriteJSON(cfg.Stream.Out)
+	} else {
+		err = report.WriteTable(cfg.Stream.Out)
+	}
+	if err != nil {
+		return err
+	}
+	if n := report.Failed(); n > 0 {
+		return fmt.Errorf("%d of %d checks failed", n, len(report.Checks))
+	}
+	return nil
+}
+
 func runVerify(ctx context.Context, cfg *config.Config, args []string, o verifyOptions) error {
+	if o.repository != "" || isRemoteContract(args) {
+		return runVerifyRemote(ctx, cfg, args, o)
+	}
 	var err error
 	o.c, err = LoadContractFromArgs(args)
 	if err != nil {
 		return err
 	}
-	if o.publicKey == "" {
-		o.publicKey, err = o.c.GetPublicKey()
+	at := time.Time{}
+	if o.at != "" {
+		if at, err = time.Parse(time.RFC3339, o.at); err != nil {
+			return fmt.Errorf("invalid --at %q, expects a RFC3339 timestamp: %w", o.at, err)
+		}
+	}
+	// the keys verifying the resource signed with keyID, the flag takes precedence
+	keys := func(keyID string) ([]string, error) {
+		if o.publicKey != "" {
+			return []string{o.publicKey}, nil
+		}
+		return o.c.GetPublicKeys(keyID, at)
+	}
+	annotations := o.c.GetAnnotations()
+
+	if err := o.c.VerifyResources(ctx, func(ctx context.Context, blobRef, sigRef, keyID string) error {
+		candidates, err := keys(keyID)
 		if err != nil {
 			return err
 		}
+		// the Tekton trusted resources signature, embedded on the resource
+		embedded, err := trustedresources.Signed(blobRef)
+		if err != nil {
+			return err
+		}
+		if embedded {
+			fmt.Fprintf(os.Stderr, "# Verifying resource %q embedded signature...\n", blobRef)
+			if err := trustedresources.Verify(ctx, candidates, annotations, blobRef); err != nil {
+				return fmt.Errorf("resource %s: %w", blobRef, err)
+			}
+			if sigRef == "" {
+				return nil
+			}
+		}
+		fmt.Fprintf(os.Stderr, "# Verifying resource %q against signature %q...\n", blobRef, sigRef)
+		return attestation.VerifyWithKeys(ctx, candidates, annotations, blobRef, sigRef)
+	}); err != nil {
+		return err
 	}
-	cfg.Infof("# Public-Key: %q\n", o.publicKey)
 
-	helper, err := attestation.NewAttestation(o.publicKey)
+	candidates, err := keys("")
 	if err != nil {
 		return err
 	}
-	return o.c.VerifyResources(ctx, func(ctx context.Context, blobRef, sigRef string) error {
-		fmt.Fprintf(os.Stderr, "# Verifying resource %q against signature %q...\n", blobRef, sigRef)
-		return helper.Verify(ctx, blobRef, sigRef)
-	})
+	cfg.Infof("# Public-Keys: %q\n", candidates)
+	// the contract is verified when signed, unless its signature is required
+	contractSignature := contract.SignatureFile(o.c.File())
+	if _, err := os.Stat(contractSignature); errors.Is(err, os.ErrNotExist) && !o.requireContractSignature {
+		fmt.Fprintf(os.Stderr, "# Contract %q has no signature %q, skipping\n", o.c.File(), contractSignature)
+	} else {
+		fmt.Fprintf(os.Stderr, "# Verifying contract %q against signature %q...\n", o.c.File(), contractSignature)
+		if err := attestation.VerifyWithKeys(ctx, candidates, nil, o.c.File(), contractSignature); err != nil {
+			return fmt.Errorf("contract %s: %w", o.c.File(), err)
+		}
+	}
+	// the tarball is verified when recorded on the contract
+	if o.c.Catalog.Tarball != nil {
+		tarball := o.c.TarballFile()
+		fmt.Fprintf(os.Stderr, "# Verifying tarball %q...\n", tarball)
+		if err := o.c.VerifyTarball(tarball); err != nil {
+			return err
+		}
+		if _, err := os.Stat(contract.SignatureFile(tarball)); errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "# Tarball %q has no signature %q, skipping\n", tarball, contract.SignatureFile(tarball))
+		} else if err := attestation.VerifyWithKeys(ctx, candidates, nil, tarball, contract.SignatureFile(tarball)); err != nil {
+			return fmt.Errorf("tarball %s: %w", tarball, err)
+		}
+	}
+	// the provenance is verified when signed
+	dir := filepath.Dir(o.c.File())
+	envelopeFile := filepath.Join(dir, provenance.EnvelopeFilename)
+	if _, err := os.Stat(envelopeFile); err != nil {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "# Verifying provenance %q...\n", envelopeFile)
+	_, err = provenance.Verify(ctx, candidates, envelopeFile, dir)
+	return err
 }
 
 // NewVerifyCmd instantiates the "verify" subcommand.
@@ -56,8 +220,8 @@ func NewVerifyCmd(cfg *config.Config) *cobra.Command {
 	o := verifyOptions{}
 
 	cmd := &cobra.Command{
-		Use:          "verify",
-		Args:         cobra.ExactArgs(1),
+		Use:          "verify [contract|directory|contract-url]",
+		Args:         cobra.MaximumNArgs(1),
 		Long:         verifyLongDescription,
 		Short:        "Verifies the resource file signature",
 		SilenceUsage: true,
@@ -66,5 +230,13 @@ func NewVerifyCmd(cfg *config.Config) *cobra.Command {
 		},
 	}
 	cmd.PersistentFlags().StringVar(&o.publicKey, "public-key", "", "path to the public key file")
+	cmd.PersistentFlags().StringVar(&o.at, "at", "", "release time (RFC3339), only the attestation keys valid at that time are used")
+	cmd.PersistentFlags().StringVar(&o.repository, "repository", "", "url of the repository hosting the remote release to verify")
+	cmd.PersistentFlags().StringVar(&o.version, "version", "", "version of the remote release to verify, with --repository")
+	cmd.PersistentFlags().StringVar(&o.output, "output", "table", "remote release report format (table or json)")
+	cmd.PersistentFlags().BoolVar(&o.requireContractSignature, "require-contract-signature", false, "fails when the contract detached signature is missing")
+	o.addCacheFlags(cmd.PersistentFlags())
+	// a single release is fetched
+	o.parallelism = 1
 	return cmd
 }
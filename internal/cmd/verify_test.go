@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	tkncli "github.com/tektoncd/cli/pkg/cli"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestVerifyContractSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+
+	dir := fs.NewDir(t, "release",
+		fs.WithFile("cosign.pub", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))))
+	defer dir.Remove()
+	file := dir.Join(contract.Filename)
+	assert.NilError(t, contract.NewContractEmpty().SaveAs(file))
+
+	cfg := &config.Config{Stream: &tkncli.Stream{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}}
+	o := verifyOptions{publicKey: dir.Join("cosign.pub")}
+
+	// the release has no contract signature
+	assert.NilError(t, runVerify(context.Background(), cfg, []string{file}, o))
+	o.requireContractSignature = true
+	assert.ErrorContains(t, runVerify(context.Background(), cfg, []string{file}, o), "contract "+file)
+
+	// the contract signature is verified when present
+	payload, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(contract.SignatureFile(file), []byte(base64.StdEncoding.EncodeToString(sig)), 0o644))
+	assert.NilError(t, runVerify(context.Background(), cfg, []string{file}, o))
+
+	// and fails when it doesn't match
+	assert.NilError(t, os.WriteFile(file, append(payload, '\n'), 0o644))
+	o.requireContractSignature = false
+	assert.ErrorContains(t, runVerify(context.Background(), cfg, []string{file}, o), "contract "+file)
+}
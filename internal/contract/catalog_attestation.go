@@ -2,17 +2,48 @@ package contract
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-errors/errors"
 )
 
-// ErrAttestationPublicKeyEmpty marks the public-key is not yet set.
-var ErrAttestationPublicKeyEmpty = errors.New("public-key is empty")
+var (
+	// ErrAttestationPublicKeyEmpty marks the public-key is not yet set.
+	ErrAttestationPublicKeyEmpty = errors.New("public-key is empty")
+	// ErrAttestationUnknownKey marks the key ID isn't part of the attestation keys, or the key
+	// is not valid at the informed time.
+	ErrAttestationUnknownKey = errors.New("unknown public-key")
+)
 
 // Attestation holds the attributes needed for the software supply chain security.
 type Attestation struct {
-	// PublicKey path to the public key file, KMS URI or Kubernetes Secret.
+	// PublicKey path to the public key file, KMS URI, Kubernetes Secret or the inline PEM key.
 	PublicKey string `json:"publicKey"`
+	// PublicKeys trusted public keys identified by ID and valid on a time window, allowing
+	// maintainers to rotate the signing key without breaking older releases verification.
+	PublicKeys []PublicKey `json:"publicKeys"`
+	// Annotations expected on every signed resource, checked while verifying the signatures.
+	Annotations map[string]string `json:"annotations"`
+}
+
+// PublicKey trusted public key, valid for the releases published on its validity window.
+type PublicKey struct {
+	// ID key identifier, referenced by the resources signed with the key.
+	ID string `json:"id"`
+	// Key path to the public key file, KMS URI, Kubernetes Secret or the inline PEM key.
+	Key string `json:"key"`
+	// NotBefore start of the key validity window, unbounded when empty.
+	NotBefore *time.Time `json:"notBefore"`
+	// NotAfter end of the key validity window, unbounded when empty.
+	NotAfter *time.Time `json:"notAfter"`
+}
+
+// ValidAt asserts the key is valid at the informed time, a zero time matches every window.
+func (k PublicKey) ValidAt(at time.Time) bool {
+	if at.IsZero() {
+		return true
+	}
+	return (k.NotBefore == nil || !at.Before(*k.NotBefore)) && (k.NotAfter == nil || !at.After(*k.NotAfter))
 }
 
 // GetPublicKey accessor to the attestation's public-key, emits error when not set.
@@ -22,3 +53,45 @@ func (c *Contract) GetPublicKey() (string, error) {
 	}
 	return c.Catalog.Attestation.PublicKey, nil
 }
+
+// GetPublicKeys returns the keys verifying a resource signed at the informed time, either the
+// key identified by keyID or every key valid at that time (including the single public-key).
+func (c *Contract) GetPublicKeys(keyID string, at time.Time) ([]string, error) {
+	a := c.Catalog.Attestation
+	if a == nil || (a.PublicKey == "" && len(a.PublicKeys) == 0) {
+		return nil, fmt.Errorf("%w: .catalog.attestation is not set", ErrAttestationPublicKeyEmpty)
+	}
+	if keyID != "" {
+		for _, k := range a.PublicKeys {
+			if k.ID != keyID {
+				continue
+			}
+			if !k.ValidAt(at) {
+				return nil, fmt.Errorf("%w: key %q is not valid at %s", ErrAttestationUnknownKey, keyID, at.Format(time.RFC3339))
+			}
+			return []string{k.Key}, nil
+		}
+		return nil, fmt.Errorf("%w: key %q is not part of .catalog.attestation.publicKeys", ErrAttestationUnknownKey, keyID)
+	}
+	keys := []string{}
+	if a.PublicKey != "" {
+		keys = append(keys, a.PublicKey)
+	}
+	for _, k := range a.PublicKeys {
+		if k.ValidAt(at) {
+			keys = append(keys, k.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no key valid at %s", ErrAttestationUnknownKey, at.Format(time.RFC3339))
+	}
+	return keys, nil
+}
+
+// GetAnnotations accessor to the annotations expected on the signed resources.
+func (c *Contract) GetAnnotations() map[string]string {
+	if c.Catalog.Attestation == nil {
+		return nil
+	}
+	return c.Catalog.Attestation.Annotations
+}
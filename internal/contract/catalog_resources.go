@@ -23,6 +23,30 @@ type TektonResource struct {
 	// location to the signature file. By default, it uses the ".filename" attributed
 	// followed by ".sig" extension.
 	Signature string `json:"signature"`
+	// KeyID identifier of the attestation public-key signing the resource, when empty every
+	// attestation key is tried.
+	KeyID string `json:"keyID"`
+}
+
+// Resource types, the lowercase plural of the resource kind, as in the catalog folders.
+const (
+	TypeTasks       = "tasks"
+	TypePipelines   = "pipelines"
+	TypeStepActions = "stepactions"
+)
+
+// ResourceTypes all the supported resource types.
+var ResourceTypes = []string{TypeTasks, TypePipelines, TypeStepActions}
+
+// ValidateResourceType asserts the resource type is supported.
+func ValidateResourceType(resourceType string) error {
+	for _, t := range ResourceTypes {
+		if resourceType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: resource type %q, expects one of %s",
+		ErrTektonResourceUnsupported, resourceType, strings.Join(ResourceTypes, ", "))
 }
 
 // Resources inventory of all Tekton resources managed by the repository.
@@ -31,6 +55,37 @@ type Resources struct {
 	Tasks []*TektonResource `json:"tasks"`
 	// Pipelines List of Tekton Pipelines.
 	Pipelines []*TektonResource `json:"pipelines"`
+	// StepActions List of Tekton StepActions.
+	StepActions []*TektonResource `json:"stepactions"`
+}
+
+// All returns every resource, tasks, pipelines and stepactions.
+func (r *Resources) All() []*TektonResource {
+	if r == nil {
+		return nil
+	}
+	all := make([]*TektonResource, 0, len(r.Tasks)+len(r.Pipelines)+len(r.StepActions))
+	all = append(all, r.Tasks...)
+	all = append(all, r.Pipelines...)
+	return append(all, r.StepActions...)
+}
+
+// OfType returns the resources of the informed type, every resource when empty.
+func (r *Resources) OfType(resourceType string) []*TektonResource {
+	if r == nil {
+		return nil
+	}
+	switch resourceType {
+	case TypeTasks:
+		return r.Tasks
+	case TypePipelines:
+		return r.Pipelines
+	case TypeStepActions:
+		return r.StepActions
+	case "":
+		return r.All()
+	}
+	return nil
 }
 
 // ResourceSignFn function to perform the resource (file) signature. Parameters:
@@ -42,17 +97,19 @@ type ResourceSignFn func(_, _ string) error
 //   - context: shared context
 //   - resource-file: the resource file
 //   - signature-file: the respective signature file
-type ResourceVerifySignatureFn func(_ context.Context, _, _ string) error
+//   - key-id: the attestation key signing the resource, may be empty
+type ResourceVerifySignatureFn func(_ context.Context, _, _, _ string) error
 
 // SignResources runs the informed function against each catalog resource, the expected
-// signature file created is updated on "this" contract instance.
-func (c *Contract) SignResources(fn ResourceSignFn) error {
-	for _, r := range append(c.Catalog.Resources.Tasks, c.Catalog.Resources.Pipelines...) {
-		signatureFile := fmt.Sprintf("%s.%s", r.Filename, SignatureExtension)
+// signature file created, and the signing key ID, are updated on "this" contract instance.
+func (c *Contract) SignResources(keyID string, fn ResourceSignFn) error {
+	for _, r := range c.Catalog.Resources.All() {
+		signatureFile := SignatureFile(r.Filename)
 		if err := fn(r.Filename, signatureFile); err != nil {
 			return err
 		}
 		r.Signature = signatureFile
+		r.KeyID = keyID
 	}
 	return nil
 }
@@ -60,24 +117,46 @@ func (c *Contract) SignResources(fn ResourceSignFn) error {
 // VerifyResources runs the informed function against each catalog resource, when error is
 // returned the signature verification process fail.
 func (c *Contract) VerifyResources(ctx context.Context, fn ResourceVerifySignatureFn) error {
-	for _, r := range append(c.Catalog.Resources.Tasks, c.Catalog.Resources.Pipelines...) {
-		if err := fn(ctx, r.Filename, r.Signature); err != nil {
+	for _, r := range c.Catalog.Resources.All() {
+		if err := fn(ctx, r.Filename, r.Signature, r.KeyID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// UpdateChecksums recomputes every resource checksum, the resource files are located relative
+// to the informed directory. Used when the resource files are modified after added to the
+// contract, as in embedding the signature.
+func (c *Contract) UpdateChecksums(dir string) error {
+	for _, r := range c.Catalog.Resources.All() {
+		sum, err := CalculateSHA256Sum(filepath.Join(dir, r.Filename))
+		if err != nil {
+			return err
+		}
+		r.Checksum = sum
+	}
+	return nil
+}
+
 // AddResourceFile adds a resource file on the contract, making sure it's a Tekton resource
 // file and uses the "kind" to guide on which attribute the resource will be appended.
 func (c *Contract) AddResourceFile(resourceFile, version string) error {
-	// parsing the resource as a kubernetes unstructured type to read it's name and kind
-	u, err := resource.ReadAndDecodeResourceFile(resourceFile)
+	// making sure it's a tekton kubernetes resource, on the supported versions, before decoding
+	// it with the Tekton schema which doesn't know the unsupported versions
+	docs, err := resource.ReadDocuments(resourceFile)
 	if err != nil {
 		return err
 	}
-	// making sure it's a tekton kubernetes resource, on the supported versions
-	if err = isResourceSupported(u); err != nil {
+	if len(docs) == 0 {
+		return fmt.Errorf("%w: %s has no resource", ErrTektonResourceUnsupported, resourceFile)
+	}
+	if err = IsResourceSupported(docs[0].Object); err != nil {
+		return err
+	}
+	// parsing the resource as a kubernetes unstructured type to read it's name and kind
+	u, err := resource.ReadAndDecodeResourceFile(resourceFile)
+	if err != nil {
 		return err
 	}
 
@@ -100,6 +179,8 @@ func (c *Contract) AddResourceFile(resourceFile, version string) error {
 		c.Catalog.Resources.Tasks = append(c.Catalog.Resources.Tasks, &tr)
 	case "Pipeline":
 		c.Catalog.Resources.Pipelines = append(c.Catalog.Resources.Pipelines, &tr)
+	case "StepAction":
+		c.Catalog.Resources.StepActions = append(c.Catalog.Resources.StepActions, &tr)
 	default:
 		return fmt.Errorf("%w: resource kind %q", ErrTektonResourceUnsupported, kind)
 	}
@@ -0,0 +1,65 @@
+package contract
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-errors/errors"
+)
+
+// ErrTarballChecksum marks the resources tarball doesn't match the checksum recorded on the
+// contract.
+var ErrTarballChecksum = errors.New("resources tarball checksum mismatch")
+
+// Tarball describes the resources tarball released with the contract, the contract signature
+// covers the tarball contents through its checksum.
+type Tarball struct {
+	// Name tarball file name, next to the contract.
+	Name string `json:"name"`
+	// Checksum tarball SHA256 sum.
+	Checksum string `json:"checksum"`
+}
+
+// File returns the contract file location, empty when the contract isn't loaded from a file.
+func (c *Contract) File() string {
+	return c.file
+}
+
+// SignatureFile returns the detached signature location of the informed file.
+func SignatureFile(file string) string {
+	return fmt.Sprintf("%s.%s", file, SignatureExtension)
+}
+
+// TarballFile returns the resources tarball location, next to the contract file.
+func (c *Contract) TarballFile() string {
+	name := ResourcesName
+	if c.Catalog.Tarball != nil && c.Catalog.Tarball.Name != "" {
+		name = c.Catalog.Tarball.Name
+	}
+	return filepath.Join(filepath.Dir(c.file), name)
+}
+
+// SetTarball records the resources tarball name and checksum on the contract.
+func (c *Contract) SetTarball(file string) error {
+	sum, err := CalculateSHA256Sum(file)
+	if err != nil {
+		return err
+	}
+	c.Catalog.Tarball = &Tarball{Name: filepath.Base(file), Checksum: sum}
+	return nil
+}
+
+// VerifyTarball asserts the resources tarball matches the checksum recorded on the contract.
+func (c *Contract) VerifyTarball(file string) error {
+	if c.Catalog.Tarball == nil || c.Catalog.Tarball.Checksum == "" {
+		return fmt.Errorf("%w: .catalog.tarball is not set", ErrTarballChecksum)
+	}
+	sum, err := CalculateSHA256Sum(file)
+	if err != nil {
+		return err
+	}
+	if sum != c.Catalog.Tarball.Checksum {
+		return fmt.Errorf("%w: %s checksum %s, expected %s", ErrTarballChecksum, file, sum, c.Catalog.Tarball.Checksum)
+	}
+	return nil
+}
@@ -3,8 +3,6 @@ package contract
 import (
 	"bytes"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path"
 
@@ -35,6 +33,7 @@ type Catalog struct {
 	Repository  *Repository  `json:"repository"`  // repository long description
 	Attestation *Attestation `json:"attestation"` // software supply provenance
 	Resources   *Resources   `json:"resources"`   // inventory of Tekton resources
+	Tarball     *Tarball     `json:"tarball"`     // resources tarball released with the contract
 }
 
 // Contract contains a versioned catalog.
@@ -106,24 +105,12 @@ func NewContractFromFile(location string) (*Contract, error) {
 		return nil, err
 	}
 
-	return NewContractFromData(payload)
-}
-
-// NewContractFromURL instantiates a new Contract{} from a URL.
-func NewContractFromURL(url string) (*Contract, error) {
-	resp, err := http.Get(url) // nolint:gosec,noctx
-	if err != nil {
-		return nil, fmt.Errorf("could not load contract from %s: %w", url, err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("status error: %v", resp.StatusCode)
-	}
-	data, err := io.ReadAll(resp.Body)
+	c, err := NewContractFromData(payload)
 	if err != nil {
-		return nil, fmt.Errorf("could not load contract from %s: %w", url, err)
+		return nil, err
 	}
-	return NewContractFromData(data)
+	c.file = file
+	return c, nil
 }
 
 // NewContractFromData instantiates a new Contract{} from a YAML payload.
@@ -1,8 +1,10 @@
 package contract
 
 import (
+	"os"
 	"path"
 	"testing"
+	"time"
 
 	o "github.com/onsi/gomega"
 )
@@ -61,3 +63,112 @@ func TestNewContractFromFile(t *testing.T) {
 		g.Expect(c.Catalog.Resources).ToNot(o.BeNil())
 	})
 }
+
+func TestContractTarball(t *testing.T) {
+	g := o.NewWithT(t)
+
+	dir := t.TempDir()
+	tarball := path.Join(dir, ResourcesName)
+	g.Expect(os.WriteFile(tarball, []byte("resources"), 0o600)).To(o.Succeed())
+
+	c := NewContractEmpty()
+	c.file = path.Join(dir, Filename)
+	g.Expect(c.VerifyTarball(tarball)).To(o.MatchError(ErrTarballChecksum))
+
+	g.Expect(c.SetTarball(tarball)).To(o.Succeed())
+	g.Expect(c.TarballFile()).To(o.Equal(tarball))
+	g.Expect(c.Catalog.Tarball.Checksum).To(o.Equal("41d311a605520fc7b8b9a980a79437a26e26cebcbfdd569dd78d30f7cb3e7237"))
+	g.Expect(c.VerifyTarball(tarball)).To(o.Succeed())
+
+	g.Expect(os.WriteFile(tarball, []byte("tampered"), 0o600)).To(o.Succeed())
+	g.Expect(c.VerifyTarball(tarball)).To(o.MatchError(o.ContainSubstring("resources tarball checksum mismatch")))
+	g.Expect(SignatureFile(tarball)).To(o.Equal(tarball + ".sig"))
+}
+
+func TestContractPublicKeys(t *testing.T) {
+	g := o.NewWithT(t)
+
+	c, err := NewContractFromData([]byte(`version: v1
+catalog:
+  attestation:
+    publickeys:
+      - id: "2023"
+        key: keys/2023.pub
+        notafter: 2023-12-31T23:59:59Z
+      - id: "2024"
+        key: |
+          -----BEGIN PUBLIC KEY-----
+          …
+          -----END PUBLIC KEY-----
+        notbefore: 2024-01-01T00:00:00Z
+    annotations:
+      team: tekton-ecosystem
+`))
+	g.Expect(err).ToNot(o.HaveOccurred())
+	g.Expect(c.GetAnnotations()).To(o.Equal(map[string]string{"team": "tekton-ecosystem"}))
+
+	keys, err := c.GetPublicKeys("", time.Time{})
+	g.Expect(err).ToNot(o.HaveOccurred())
+	g.Expect(keys).To(o.HaveLen(2))
+
+	keys, err = c.GetPublicKeys("", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+	g.Expect(err).ToNot(o.HaveOccurred())
+	g.Expect(keys).To(o.Equal([]string{"keys/2023.pub"}))
+
+	keys, err = c.GetPublicKeys("2024", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	g.Expect(err).ToNot(o.HaveOccurred())
+	g.Expect(keys).To(o.HaveLen(1))
+	g.Expect(keys[0]).To(o.HavePrefix("-----BEGIN PUBLIC KEY-----"))
+
+	_, err = c.GetPublicKeys("2024", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+	g.Expect(err).To(o.MatchError(o.ContainSubstring(`key "2024" is not valid at 2023-06-01T00:00:00Z`)))
+	_, err = c.GetPublicKeys("2025", time.Time{})
+	g.Expect(err).To(o.MatchError(o.ContainSubstring(`key "2025" is not part of .catalog.attestation.publicKeys`)))
+
+	_, err = NewContractEmpty().GetPublicKeys("", time.Time{})
+	g.Expect(err).To(o.MatchError(o.ContainSubstring(".catalog.attestation is not set")))
+}
+
+func TestContractStepActions(t *testing.T) {
+	g := o.NewWithT(t)
+
+	dir := path.Join(t.TempDir(), "foo")
+	g.Expect(os.MkdirAll(dir, 0o755)).To(o.Succeed())
+	stepActionFile := path.Join(dir, "foo.yaml")
+	g.Expect(os.WriteFile(stepActionFile, []byte(`apiVersion: tekton.dev/v1alpha1
+kind: StepAction
+metadata:
+  name: foo
+spec:
+  image: registry.access.redhat.com/ubi9/ubi-minimal
+  script: echo foo
+`), 0o600)).To(o.Succeed())
+
+	c := NewContractEmpty()
+	g.Expect(c.AddResourceFile(stepActionFile, "0.1.0")).To(o.Succeed())
+	g.Expect(c.Catalog.Resources.StepActions).To(o.HaveLen(1))
+	g.Expect(c.Catalog.Resources.StepActions[0].Filename).To(o.Equal("stepactions/foo/foo.yaml"))
+	g.Expect(c.Catalog.Resources.OfType(TypeStepActions)).To(o.HaveLen(1))
+	g.Expect(c.Catalog.Resources.OfType(TypeTasks)).To(o.BeEmpty())
+	g.Expect(c.Catalog.Resources.All()).To(o.HaveLen(1))
+
+	payload, err := c.Print()
+	g.Expect(err).ToNot(o.HaveOccurred())
+	g.Expect(string(payload)).To(o.ContainSubstring("stepactions:"))
+
+	// StepAction is only supported as v1alpha1
+	v1beta1File := path.Join(dir, "v1beta1.yaml")
+	g.Expect(os.WriteFile(v1beta1File, []byte(`apiVersion: tekton.dev/v1beta1
+kind: StepAction
+metadata:
+  name: foo
+spec:
+  image: registry.access.redhat.com/ubi9/ubi-minimal
+`), 0o600)).To(o.Succeed())
+	err = c.AddResourceFile(v1beta1File, "0.1.0")
+	g.Expect(err).To(o.MatchError(ErrTektonResourceUnsupported))
+	g.Expect(err).To(o.MatchError(o.ContainSubstring(`unsupported version "v1beta1" for StepAction (expects v1alpha1)`)))
+
+	g.Expect(ValidateResourceType(TypeStepActions)).To(o.Succeed())
+	g.Expect(ValidateResourceType("triggers")).To(o.MatchError(ErrTektonResourceUnsupported))
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"slices"
+	"strings"
 
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -16,23 +18,33 @@ import (
 // Kubernetes CRD, or not a Tekton API on supported versions, etc.
 var ErrTektonResourceUnsupported = errors.New("tekton resource not supported")
 
-// isResourceSupported inspects the unstructured to assert it's a Tekton resource, and its
-// version is supported by this program.
-func isResourceSupported(u *unstructured.Unstructured) error {
+// supportedVersions the supported API versions of each Tekton resource kind, StepAction is only
+// served as v1alpha1 by the Tekton Pipelines release in use.
+var supportedVersions = map[string][]string{
+	"Task":       {"v1", "v1beta1"},
+	"Pipeline":   {"v1", "v1beta1"},
+	"StepAction": {"v1alpha1"},
+}
+
+// IsResourceSupported inspects the unstructured to assert it's a Tekton resource, and its
+// version is supported by this program. It doesn't need the resource to be decoded, the
+// unsupported versions are not part of the Tekton schema.
+func IsResourceSupported(u *unstructured.Unstructured) error {
 	group := u.GroupVersionKind().Group
 	if group != v1.SchemeGroupVersion.Group {
 		return fmt.Errorf("%w: unsupported group %q (expects %q)",
 			ErrTektonResourceUnsupported, group, v1.SchemeGroupVersion.Group)
 	}
-	version := u.GroupVersionKind().Version
-	if version != "v1" && version != "v1beta1" {
-		return fmt.Errorf("%w: unsupported version %q",
-			ErrTektonResourceUnsupported, version)
-	}
 	kind := u.GetKind()
-	if kind != "Task" && kind != "Pipeline" {
+	versions, ok := supportedVersions[kind]
+	if !ok {
 		return fmt.Errorf("%w: unsupported kind %q", ErrTektonResourceUnsupported, kind)
 	}
+	version := u.GroupVersionKind().Version
+	if !slices.Contains(versions, version) {
+		return fmt.Errorf("%w: unsupported version %q for %s (expects %s)",
+			ErrTektonResourceUnsupported, version, kind, strings.Join(versions, " or "))
+	}
 	return nil
 }
 
@@ -0,0 +1,137 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// gitHubHost the public GitHub host, other GitHub hosts are Enterprise Server instances.
+const gitHubHost = "github.com"
+
+// Clients resolves the provider and credentials of each host, using the externals "hosts"
+// configuration, and builds the GitHub API clients on demand (one per host). Release assets
+// are downloaded with the same transport and credentials. A nil Clients uses the default
+// transport, without credentials.
+type Clients struct {
+	hosts     map[string]config.Host // hosts configuration, by lowercase name
+	transport http.RoundTripper      // transport of the GitHub API clients, default when nil
+	http      *http.Client           // http client listing releases and downloading assets
+
+	mu     sync.Mutex
+	github map[string]*api.RESTClient // GitHub API clients, by host
+	tokens map[string]string          // tokens authenticating asset downloads, by host
+}
+
+// NewClients instantiates the clients of the informed hosts, the GitHub API clients and the
+// downloads are using the informed transport (http.DefaultTransport when nil).
+func NewClients(hosts []config.Host, transport http.RoundTripper) *Clients {
+	c := &Clients{
+		hosts:     map[string]config.Host{},
+		transport: transport,
+		http:      &http.Client{Transport: transport},
+		github:    map[string]*api.RESTClient{},
+		tokens:    map[string]string{},
+	}
+	for _, h := range hosts {
+		c.hosts[strings.ToLower(h.Name)] = h
+	}
+	return c
+}
+
+// WithGitHubClient uses the informed client to reach the GitHub API of the host.
+func (c *Clients) WithGitHubClient(host string, client *api.RESTClient) *Clients {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.github[strings.ToLower(host)] = client
+	return c
+}
+
+// Provider returns the provider of the repository URL, configured for its host or inferred
+// from the URL.
+func (c *Clients) Provider(repositoryURL string) string {
+	if u, err := url.Parse(repositoryURL); err == nil {
+		if h, ok := c.hosts[strings.ToLower(u.Host)]; ok && h.Provider != "" {
+			return h.Provider
+		}
+	}
+	return providerFromURL(repositoryURL)
+}
+
+// Token returns the token configured for the host ("token-env"), empty when not configured.
+func (c *Clients) Token(host string) (string, error) {
+	h, ok := c.hosts[strings.ToLower(host)]
+	if !ok {
+		return "", nil
+	}
+	return h.Token()
+}
+
+// HTTPClient returns the http client listing releases and downloading assets.
+func (c *Clients) HTTPClient() *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c.http
+}
+
+// downloadToken returns the token authenticating the asset downloads from the host, empty
+// when none.
+func (c *Clients) downloadToken(host string) string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[strings.ToLower(host)]
+}
+
+// GitHub returns the API client of the GitHub host, either github.com or an Enterprise Server
+// instance. Without a configured token, the one resolved by the gh CLI is used (GH_TOKEN,
+// GH_ENTERPRISE_TOKEN, gh configuration). The token is also kept to download release assets
+// from the host API.
+func (c *Clients) GitHub(host string) (*api.RESTClient, error) {
+	host = strings.ToLower(host)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.github[host]; ok {
+		return client, nil
+	}
+
+	token, err := c.Token(host)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		token, _ = auth.TokenForHost(host)
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no token found for GitHub host %s, authenticate with gh or configure the host token-env", host)
+	}
+	client, err := api.NewRESTClient(api.ClientOptions{
+		Host:      host,
+		AuthToken: token,
+		Transport: c.transport,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.tokens[gitHubAPIHost(host)] = token
+	c.github[host] = client
+	return client, nil
+}
+
+// gitHubAPIHost returns the host serving the API of the GitHub host, Enterprise Server
+// instances are serving it on the same host ("/api/v3").
+func gitHubAPIHost(host string) string {
+	if host == gitHubHost {
+		return "api." + gitHubHost
+	}
+	return host
+}
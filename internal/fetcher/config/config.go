@@ -2,39 +2,211 @@
 package config
 
 import (
+	"encoding/pem"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"sigs.k8s.io/yaml"
 )
 
+const (
+	// ProviderGitHub fetches releases from a GitHub repository.
+	ProviderGitHub = "github"
+	// ProviderGitLab fetches releases from a GitLab project.
+	ProviderGitLab = "gitlab"
+	// ProviderGitea fetches releases from a Gitea repository.
+	ProviderGitea = "gitea"
+	// ProviderForgejo fetches releases from a Forgejo repository, using the Gitea API.
+	ProviderForgejo = "forgejo"
+	// ProviderOCI fetches releases pushed as OCI artifacts, on "oci://registry/repository".
+	ProviderOCI = "oci"
+)
+
+const (
+	// ChannelStable only fetches published releases, the default.
+	ChannelStable = "stable"
+	// ChannelPreRelease only fetches pre-releases (release candidates, …).
+	ChannelPreRelease = "prerelease"
+	// ChannelAll fetches every release, including pre-releases and drafts.
+	ChannelAll = "all"
+)
+
 // External is a representation of the configuration for specifying repositories we have to pull from.
 type External struct {
+	// Hosts configures the hosts serving the repositories, provider and credentials
+	Hosts []Host
 	// Repositories defines the repositories to pull from
 	Repositories []Repository
 }
 
+// Host configures a host serving repositories, for instance a GitHub Enterprise Server instance
+// or a self-hosted GitLab.
+type Host struct {
+	// Name is the host name, as in the repositories URL ("github.example.com").
+	Name string
+	// Provider defines the source backend of the repositories on the host (github, gitlab, gitea,
+	// forgejo), when empty it's inferred for the public hosts only (github.com, gitlab.com,
+	// gitea.com and codeberg.org).
+	Provider string
+	// TokenEnv is the name of the environment variable holding the host API token, when empty
+	// the default credentials are used (gh CLI configuration, GITLAB_TOKEN, …).
+	TokenEnv string `json:"token-env"`
+}
+
+// Token returns the host token, read from the "token-env" environment variable. Returns empty
+// when the host doesn't configure it, errors out when the variable is not set.
+func (h Host) Token() (string, error) {
+	if h.TokenEnv == "" {
+		return "", nil
+	}
+	token := os.Getenv(h.TokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s holding the %s token is not set", h.TokenEnv, h.Name)
+	}
+	return token, nil
+}
+
+// validate checks the host attributes.
+func (h Host) validate() error {
+	if h.Name == "" || strings.Contains(h.Name, "/") {
+		return fmt.Errorf("invalid host name %q, expects a bare host name (github.example.com)", h.Name)
+	}
+	switch h.Provider {
+	case "", ProviderGitHub, ProviderGitLab, ProviderGitea, ProviderForgejo:
+		return nil
+	}
+	return fmt.Errorf("invalid provider %q for host %s, expects %s, %s, %s or %s",
+		h.Provider, h.Name, ProviderGitHub, ProviderGitLab, ProviderGitea, ProviderForgejo)
+}
+
+// Host returns the configuration of the informed host name.
+func (e External) Host(name string) (Host, bool) {
+	for _, h := range e.Hosts {
+		if strings.EqualFold(h.Name, name) {
+			return h, true
+		}
+	}
+	return Host{}, false
+}
+
 // Repository represent a git repository.
 type Repository struct {
 	Name string
 	URL  string
-	// Type defines the type to fetch (Task, Pipeline, …)
+	// Provider defines the source backend hosting the repository (github, gitlab, gitea, forgejo, oci), when
+	// empty it's taken from the host configuration, or inferred for the OCI and public hosts URL.
+	Provider string
+	// Types defines the resource types to fetch (tasks, pipelines or stepactions), all of them
+	// when empty.
 	Types                []string
 	IgnoreVersions       []string `json:"ignore-versions"`
 	CatalogName          string   `json:"catalog-name"`
 	ResourcesTarballName string   `json:"resources-tarball-name"`
+	// MaxReleases limits the amount of most recent releases fetched, unlimited when zero.
+	MaxReleases int `json:"max-releases"`
+	// Since ignores releases published before the informed date ("2006-01-02" or RFC3339).
+	Since string `json:"since"`
+	// Versions semantic version constraint the release tags must satisfy, for instance
+	// ">=0.3.0 <2.0.0" or "~1.4".
+	Versions string `json:"versions"`
+	// Channel defines which kind of releases are fetched (stable, prerelease or all), by
+	// default only stable releases.
+	Channel string `json:"channel"`
+	// PublicKey trusted public key of the repository, either the path to the key file (relative
+	// to the externals configuration) or the inline PEM. When set every resource must carry a
+	// valid signature, the public key informed by the contracts is not trusted.
+	PublicKey string `json:"public-key"`
+	// RequireSignatures demands the contract and the resources tarball of each release to carry
+	// a detached signature (".sig" release asset) made with the trusted public key.
+	RequireSignatures bool `json:"require-signatures"`
 }
 
-// setDefaults sets the default values for the configuration.
-func setDefaults(e External) External {
+// InlinePublicKey asserts the "public-key" attribute holds the PEM encoded key, instead of a
+// file location.
+func (r Repository) InlinePublicKey() bool {
+	return strings.HasPrefix(strings.TrimSpace(r.PublicKey), "-----BEGIN")
+}
+
+// VersionsConstraint parses the "versions" attribute, returns nil when not set.
+func (r Repository) VersionsConstraint() (*VersionConstraint, error) {
+	if r.Versions == "" {
+		return nil, nil
+	}
+	return ParseVersionConstraint(r.Versions)
+}
+
+// SinceTime parses the "since" attribute, returns zero time when not set.
+func (r Repository) SinceTime() (time.Time, error) {
+	if r.Since == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, r.Since); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid since %q for repository %s, expects a date (%s) or RFC3339 timestamp",
+		r.Since, r.URL, time.DateOnly)
+}
+
+// ValidateChannel asserts the informed channel is supported, empty means the default.
+func ValidateChannel(channel string) error {
+	switch channel {
+	case "", ChannelStable, ChannelPreRelease, ChannelAll:
+		return nil
+	}
+	return fmt.Errorf("invalid channel %q, expects %s, %s or %s",
+		channel, ChannelStable, ChannelPreRelease, ChannelAll)
+}
+
+// validate checks the repository attributes.
+func (r Repository) validate() error {
+	if r.MaxReleases < 0 {
+		return fmt.Errorf("invalid max-releases %d for repository %s", r.MaxReleases, r.URL)
+	}
+	if _, err := r.SinceTime(); err != nil {
+		return err
+	}
+	if err := ValidateChannel(r.Channel); err != nil {
+		return fmt.Errorf("%w for repository %s", err, r.URL)
+	}
+	for _, t := range r.Types {
+		if err := contract.ValidateResourceType(t); err != nil {
+			return fmt.Errorf("%w for repository %s", err, r.URL)
+		}
+	}
+	if r.RequireSignatures && r.PublicKey == "" {
+		return fmt.Errorf("require-signatures needs a public-key for repository %s", r.URL)
+	}
+	if r.InlinePublicKey() {
+		if block, _ := pem.Decode([]byte(r.PublicKey)); block == nil {
+			return fmt.Errorf("invalid inline public-key for repository %s, expects a PEM encoded key", r.URL)
+		}
+	}
+	_, err := r.VersionsConstraint()
+	return err
+}
+
+// setDefaults sets the default values for the configuration, the public-key files are relative
+// to the configuration folder.
+func setDefaults(e External, dir string) External {
 	for i, r := range e.Repositories {
+		if r.PublicKey != "" && !r.InlinePublicKey() && !filepath.IsAbs(r.PublicKey) {
+			r.PublicKey = filepath.Join(dir, r.PublicKey)
+		}
 		if r.CatalogName == "" {
 			r.CatalogName = contract.Filename
 		}
 		if r.ResourcesTarballName == "" {
 			r.ResourcesTarballName = contract.ResourcesName
 		}
+		if r.Channel == "" {
+			r.Channel = ChannelStable
+		}
 		e.Repositories[i] = r
 	}
 	return e
@@ -49,6 +221,21 @@ func LoadExternal(filename string) (External, error) {
 	if err := yaml.Unmarshal(data, &c); err != nil {
 		return External{}, fmt.Errorf("could not load external configuration from %s: %w", filename, err)
 	}
-	c = setDefaults(c)
+	hosts := map[string]bool{}
+	for _, h := range c.Hosts {
+		if err := h.validate(); err != nil {
+			return External{}, fmt.Errorf("invalid external configuration %s: %w", filename, err)
+		}
+		if hosts[strings.ToLower(h.Name)] {
+			return External{}, fmt.Errorf("invalid external configuration %s: duplicated host %s", filename, h.Name)
+		}
+		hosts[strings.ToLower(h.Name)] = true
+	}
+	for _, r := range c.Repositories {
+		if err := r.validate(); err != nil {
+			return External{}, fmt.Errorf("invalid external configuration %s: %w", filename, err)
+		}
+	}
+	c = setDefaults(c, filepath.Dir(filename))
 	return c, nil
 }
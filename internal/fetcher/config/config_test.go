@@ -27,6 +27,19 @@ func TestLoadExternalValid(t *testing.T) {
 	}
 }
 
+func TestLoadExternalPublicKey(t *testing.T) {
+	e, err := config.LoadExternal(filepath.Join("testdata", "external.publickey.valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := e.Repositories[0]; r.InlinePublicKey() || r.PublicKey != filepath.Join("testdata", "keys", "cosign.pub") {
+		t.Fatalf("public-key file should be relative to the configuration: %q", r.PublicKey)
+	}
+	if r := e.Repositories[1]; !r.InlinePublicKey() {
+		t.Fatalf("public-key should be inline: %q", r.PublicKey)
+	}
+}
+
 func TestLoadExternalInvalid(t *testing.T) {
 	paths, err := filepath.Glob(filepath.Join("testdata", "invalid.external*.yaml"))
 	if err != nil {
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// VersionConstraint semantic version constraint expression evaluated against release tags,
+// for instance ">=0.3.0 <2.0.0", "~1.4" or "^1.2 || 0.1.5". Space separated terms must all
+// match, while "||" separates alternatives. Supported operators are "=", "!=", ">", ">=",
+// "<", "<=", "~" (patch updates) and "^" (no breaking changes). Partial versions without
+// operator ("1.4") match all versions sharing the informed segments.
+type VersionConstraint struct {
+	expr string       // original expression
+	r    semver.Range // expression compiled as a range
+}
+
+// String returns the original expression.
+func (c *VersionConstraint) String() string {
+	return c.expr
+}
+
+// Check asserts the informed tag satisfies the constraint, the tag may have a "v" prefix.
+// Tags which aren't semantic versions never satisfy the constraint.
+func (c *VersionConstraint) Check(tag string) bool {
+	v, err := semver.ParseTolerant(tag)
+	if err != nil {
+		return false
+	}
+	return c.r(v)
+}
+
+// versionOperators the operators supported, longest first to avoid matching a prefix.
+var versionOperators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+// ParseVersionConstraint compiles the informed constraint expression.
+func ParseVersionConstraint(expr string) (*VersionConstraint, error) {
+	var r semver.Range
+	for _, alternative := range strings.Split(expr, "||") {
+		terms, err := splitVersionTerms(alternative)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions constraint %q: %w", expr, err)
+		}
+		var and semver.Range
+		for _, term := range terms {
+			tr, err := parseVersionTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid versions constraint %q: %w", expr, err)
+			}
+			if and == nil {
+				and = tr
+			} else {
+				and = and.AND(tr)
+			}
+		}
+		if r == nil {
+			r = and
+		} else {
+			r = r.OR(and)
+		}
+	}
+	return &VersionConstraint{expr: expr, r: r}, nil
+}
+
+// splitVersionTerms splits the space (or comma) separated terms, joining operators spaced from
+// their version (">= 1.0").
+func splitVersionTerms(s string) ([]string, error) {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+	terms := []string{}
+	for i := 0; i < len(fields); i++ {
+		term := fields[i]
+		for _, op := range versionOperators {
+			if term == op {
+				if i+1 >= len(fields) {
+					return nil, fmt.Errorf("operator %q without version", op)
+				}
+				i++
+				term += fields[i]
+				break
+			}
+		}
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// parseVersionTerm compiles a single "operator + version" term.
+func parseVersionTerm(term string) (semver.Range, error) {
+	op := ""
+	for _, o := range versionOperators {
+		if strings.HasPrefix(term, o) {
+			op = o
+			break
+		}
+	}
+	v, segments, err := parsePartialVersion(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case ">":
+		return func(o semver.Version) bool { return o.GT(v) }, nil
+	case ">=":
+		return func(o semver.Version) bool { return o.GTE(v) }, nil
+	case "<":
+		return func(o semver.Version) bool { return o.LT(v) }, nil
+	case "<=":
+		return func(o semver.Version) bool { return o.LTE(v) }, nil
+	case "!=":
+		return func(o semver.Version) bool { return o.NE(v) }, nil
+	case "~":
+		// patch level changes, or minor when only the major is informed
+		upper := semver.Version{Major: v.Major, Minor: v.Minor + 1}
+		if segments == 1 {
+			upper = semver.Version{Major: v.Major + 1}
+		}
+		return between(v, upper), nil
+	case "^":
+		// changes that do not modify the left-most non-zero segment
+		var upper semver.Version
+		switch {
+		case v.Major > 0 || segments == 1:
+			upper = semver.Version{Major: v.Major + 1}
+		case v.Minor > 0 || segments == 2:
+			upper = semver.Version{Minor: v.Minor + 1}
+		default:
+			upper = semver.Version{Patch: v.Patch + 1}
+		}
+		return between(v, upper), nil
+	default:
+		// "=" or no operator, partial versions match the whole informed segment
+		switch segments {
+		case 1:
+			return between(v, semver.Version{Major: v.Major + 1}), nil
+		case 2:
+			return between(v, semver.Version{Major: v.Major, Minor: v.Minor + 1}), nil
+		}
+		return func(o semver.Version) bool { return o.EQ(v) }, nil
+	}
+}
+
+// between matches versions greater or equal than lower and strictly lower than upper.
+func between(lower, upper semver.Version) semver.Range {
+	return func(o semver.Version) bool { return o.GTE(lower) && o.LT(upper) }
+}
+
+// parsePartialVersion parses a version which may lack minor and patch segments, returns the
+// version and the amount of segments informed.
+func parsePartialVersion(s string) (semver.Version, int, error) {
+	s = strings.TrimPrefix(s, "v")
+	core := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '+' })
+	if s == "" || len(core) == 0 {
+		return semver.Version{}, 0, fmt.Errorf("missing version")
+	}
+	segments := strings.Count(core[0], ".") + 1
+	if segments < 3 && core[0] != s {
+		return semver.Version{}, 0, fmt.Errorf("partial version %q can't have pre-release or build", s)
+	}
+	v, err := semver.ParseTolerant(s)
+	if err != nil {
+		return semver.Version{}, 0, err
+	}
+	return v, segments, nil
+}
@@ -0,0 +1,65 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+func TestVersionConstraint(t *testing.T) {
+	tests := []struct {
+		expr     string
+		accepted []string
+		rejected []string
+	}{{
+		expr:     ">=0.3.0 <2.0.0",
+		accepted: []string{"0.3.0", "v0.3.1", "v1.9.9"},
+		rejected: []string{"v0.2.9", "2.0.0", "v2.1.0"},
+	}, {
+		expr:     ">= 0.3, < 2",
+		accepted: []string{"v0.3.0", "1.0.0"},
+		rejected: []string{"v0.2.0", "v2.0.0"},
+	}, {
+		expr:     "~1.4",
+		accepted: []string{"v1.4.0", "1.4.12"},
+		rejected: []string{"v1.3.9", "v1.5.0"},
+	}, {
+		expr:     "^0.3.1",
+		accepted: []string{"v0.3.1", "v0.3.8"},
+		rejected: []string{"v0.3.0", "v0.4.0"},
+	}, {
+		expr:     "^1.2 || 0.1",
+		accepted: []string{"v1.2.0", "v1.9.0", "v0.1.7"},
+		rejected: []string{"v2.0.0", "v0.2.0", "v1.1.0"},
+	}, {
+		expr:     "!=1.0.1",
+		accepted: []string{"v1.0.0", "v1.0.2"},
+		rejected: []string{"v1.0.1", "latest"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := config.ParseVersionConstraint(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range tt.accepted {
+				if !c.Check(v) {
+					t.Errorf("%s should satisfy %q", v, tt.expr)
+				}
+			}
+			for _, v := range tt.rejected {
+				if c.Check(v) {
+					t.Errorf("%s should not satisfy %q", v, tt.expr)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionConstraintInvalid(t *testing.T) {
+	for _, expr := range []string{"", ">=", "~foo", "1.0 ||", "^1.2-rc.1"} {
+		if _, err := config.ParseVersionConstraint(expr); err == nil {
+			t.Errorf("Should have errored out on %q", expr)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+)
+
+// ErrAssetNotFound marks a release asset which doesn't exist on the informed location.
+var ErrAssetNotFound = errors.New("asset not found")
+
+// statusError unexpected response status downloading an asset, a 404 is ErrAssetNotFound.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status error: %d", int(e))
+}
+
+func (e statusError) Is(target error) bool {
+	return target == ErrAssetNotFound && int(e) == http.StatusNotFound
+}
+
+// isGitHubAssetURL asserts the URL is a release asset on the GitHub API
+// ("/repos/<owner>/<repo>/releases/assets/<id>").
+func isGitHubAssetURL(u *url.URL) bool {
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	n := len(segments)
+	return n >= 6 && segments[n-6] == "repos" && segments[n-3] == "releases" && segments[n-2] == "assets"
+}
+
+// Open opens the informed asset location for reading, either a http(s) URL or a "oci://"
+// blob reference. GitHub API assets are downloaded with the token of the host, when known.
+func (c *Clients) Open(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if strings.HasPrefix(uri, oci.Scheme) {
+		return oci.OpenBlob(ctx, uri)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if isGitHubAssetURL(req.URL) {
+		// the API serves the asset contents instead of its metadata, redirecting to the storage
+		req.Header.Set("Accept", "application/octet-stream")
+		if token := c.downloadToken(req.URL.Host); token != "" {
+			req.Header.Set("Authorization", "token "+token)
+		}
+	}
+	resp, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, statusError(resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// FetchContract loads the contract on the informed asset location, returns the contract and
+// the SHA256 digest of its contents.
+func (c *Clients) FetchContract(ctx context.Context, uri string) (*contract.Contract, string, error) {
+	return c.fetchContract(ctx, uri, nil)
+}
+
+// fetchContract loads the contract on the informed asset location, the payload is verified
+// before parsing it when verify is not nil.
+func (c *Clients) fetchContract(ctx context.Context, uri string, verify func([]byte) error) (*contract.Contract, string, error) {
+	r, err := c.Open(ctx, uri)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not load contract from %s: %w", uri, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("could not load contract from %s: %w", uri, err)
+	}
+	if verify != nil {
+		if err := verify(data); err != nil {
+			return nil, "", err
+		}
+	}
+	parsed, err := contract.NewContractFromData(data)
+	if err != nil {
+		return nil, "", err
+	}
+	digest := sha256.Sum256(data)
+	return parsed, hex.EncodeToString(digest[:]), nil
+}
+
+// Digest downloads the asset on the informed location, returns its SHA256 digest.
+func (c *Clients) Digest(ctx context.Context, uri string) (string, error) {
+	r, err := c.Open(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("could not download %s: %w", uri, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -1,75 +1,276 @@
 package fetcher
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/openshift-pipelines/catalog-cd/internal/contract"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 )
 
-// FetchContractsFromRepository fetches contracts from a repository.
-func FetchContractsFromRepository(r config.Repository, client *api.RESTClient) (map[string]*contract.Contract, error) {
-	m := map[string]*contract.Contract{}
+// Source lists the releases published on a repository, whatever the backend hosting it.
+type Source interface {
+	// Versions returns the releases of the repository, with their assets.
+	Versions(ctx context.Context) ([]Version, error)
+}
+
+// ChannelDraft channel of draft releases, only fetched using the "all" channel policy.
+const ChannelDraft = "draft"
+
+// Release holds the contract of a repository release and the location of its resources.
+type Release struct {
+	Version      string             // release tag
+	ContractURL  string             // contract location
+	Contract     *contract.Contract // contract, once fetched
+	ContractSHA  string             // contract SHA256 digest, once fetched
+	ResourcesURL string             // resources tarball location
+	Channel      string             // release channel, stable, prerelease or draft
+	PublishedAt  time.Time          // release publication time, zero when unknown
+
+	ContractSignatureURL  string // contract detached signature location, when released
+	ResourcesSignatureURL string // resources tarball detached signature location, when released
+	// TrustedKey public key the contract and the tarball must be signed with, empty when the
+	// repository doesn't require signatures.
+	TrustedKey string
+}
+
+// NewSource instantiates the Source matching the repository provider, when the provider is
+// not set it's taken from the host configuration or inferred from the repository URL. Nil
+// clients are using the default credentials.
+func NewSource(r config.Repository, clients *Clients) (Source, error) {
+	if clients == nil {
+		clients = NewClients(nil, nil)
+	}
+	provider := r.Provider
+	if provider == "" {
+		provider = clients.Provider(r.URL)
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL %q: %w", r.URL, err)
+	}
+	switch provider {
+	case config.ProviderGitHub:
+		client, err := clients.GitHub(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return newGitHubSource(r.URL, client)
+	case config.ProviderGitLab:
+		token, err := clients.Token(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return newGitLabSource(r.URL, token, clients.HTTPClient())
+	case config.ProviderGitea, config.ProviderForgejo:
+		token, err := clients.Token(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return newGiteaSource(r.URL, token, clients.HTTPClient())
+	case config.ProviderOCI:
+		return newOCISource(r.URL)
+	case "":
+		return nil, fmt.Errorf("unknown provider for repository %s, configure the repository or host provider", r.URL)
+	default:
+		return nil, fmt.Errorf("unsupported provider %q for repository %s", provider, r.URL)
+	}
+}
+
+// publicHosts providers of the well-known public hosts, self-hosted instances must configure
+// their provider explicitly.
+var publicHosts = map[string]string{
+	gitHubHost:     config.ProviderGitHub,
+	"gitlab.com":   config.ProviderGitLab,
+	"gitea.com":    config.ProviderGitea,
+	"codeberg.org": config.ProviderForgejo,
+}
+
+// providerFromURL guesses the provider from the repository URL scheme and host, only the
+// well-known public hosts are recognized.
+func providerFromURL(repositoryURL string) string {
+	if strings.HasPrefix(repositoryURL, oci.Scheme) {
+		return config.ProviderOCI
+	}
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return ""
+	}
+	return publicHosts[strings.ToLower(u.Hostname())]
+}
 
-	if !strings.HasPrefix(r.URL, "https://github.com") {
-		return m, fmt.Errorf("non-github repository not supported: %s", r.URL)
+// ListReleases lists the repository releases selected by its configuration (channel, release
+// window, versions constraint, …), resolving the contract and resources locations without
+// downloading them. Releases are ordered from the most recent.
+func ListReleases(ctx context.Context, r config.Repository, source Source) ([]Release, error) {
+	releases := []Release{}
+
+	since, err := r.SinceTime()
+	if err != nil {
+		return nil, err
+	}
+	constraint, err := r.VersionsConstraint()
+	if err != nil {
+		return nil, err
 	}
-	repo := strings.TrimPrefix(r.URL, "https://github.com/")
-	versions, err := fetchVersions(repo, client)
+	ignored := map[string]bool{}
+	for _, v := range r.IgnoreVersions {
+		ignored[v] = true
+	}
+	versions, err := source.Versions(ctx)
 	if err != nil {
-		return m, fmt.Errorf("failed to fetch versions from %s: %w", r.URL, err)
+		return nil, fmt.Errorf("failed to fetch versions from %s: %w", r.URL, err)
 	}
+	// Most recent releases first, so the release window is applied from the latest release
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].PublishedAt.After(versions[j].PublishedAt)
+	})
 	for _, v := range versions {
-		if v.PreRelease || v.Draft {
-			// Ignore drafts or pre-releases
+		if !inChannel(v, r.Channel) {
+			// Ignore releases out of the repository channel, by default drafts and pre-releases
+			continue
+		}
+		if !since.IsZero() && !v.PublishedAt.IsZero() && v.PublishedAt.Before(since) {
+			// Release published before the window
+			continue
+		}
+		if ignored[v.TagName] || (constraint != nil && !constraint.Check(v.TagName)) {
+			// Release explicitly ignored or not satisfying the versions constraint
 			continue
 		}
-		var contractAsset Asset
-		contractFound := false
+		if r.MaxReleases > 0 && len(releases) >= r.MaxReleases {
+			break
+		}
+		var contractAsset, resourcesAsset Asset
+		contractFound, resourcesFound := false, false
+		assets := map[string]Asset{}
 		for _, a := range v.Assets {
+			assets[a.Name] = a
+			switch a.Name {
 			// catalog.yml is there for backward-compatibility
-			if a.Name == r.CatalogName || a.Name == "catalog.yml" {
+			case r.CatalogName, "catalog.yml":
+				if !contractFound || a.Name == r.CatalogName {
+					contractAsset = a
+				}
 				contractFound = true
-				contractAsset = a
-				break
+			case r.ResourcesTarballName:
+				resourcesAsset = a
+				resourcesFound = true
 			}
 		}
-		if !contractFound {
-			// FIXME(vdemeester) should we ignore or error out ?
+		if !contractFound || !resourcesFound {
+			fmt.Fprintf(os.Stderr, "# WARNING: Skipping release %s of %s, missing %s\n",
+				v.TagName, r.URL, missingAssets(r, contractFound, resourcesFound))
 			continue
 		}
-		// Load contract from asset
-		contract, err := contract.NewContractFromURL(contractAsset.DownloadURL)
-		if err != nil {
-			return m, fmt.Errorf("failed to load asset %s from %s: %w", contractAsset.Name, v.TagName, err)
+		release := Release{
+			Version:               v.TagName,
+			ContractURL:           contractAsset.DownloadURL,
+			ResourcesURL:          resourcesAsset.DownloadURL,
+			Channel:               v.Channel(),
+			PublishedAt:           v.PublishedAt,
+			ContractSignatureURL:  assets[contract.SignatureFile(contractAsset.Name)].DownloadURL,
+			ResourcesSignatureURL: assets[contract.SignatureFile(resourcesAsset.Name)].DownloadURL,
+		}
+		if r.RequireSignatures {
+			release.TrustedKey = r.PublicKey
 		}
-		m[v.TagName] = contract
+		releases = append(releases, release)
 	}
-	return m, nil
+	return releases, nil
+}
+
+// missingAssets describes the release assets missing, the contract and or the tarball.
+func missingAssets(r config.Repository, contractFound, resourcesFound bool) string {
+	missing := []string{}
+	if !contractFound {
+		missing = append(missing, r.CatalogName)
+	}
+	if !resourcesFound {
+		missing = append(missing, r.ResourcesTarballName)
+	}
+	return strings.Join(missing, " and ")
 }
 
-func fetchVersions(github string, client *api.RESTClient) ([]Version, error) {
-	versions := []Version{}
-	err := client.Get(fmt.Sprintf("repos/%s/releases", github), &versions)
+// FetchReleaseContract downloads the contract of the informed release, verifying its detached
+// signature when the release requires signatures.
+func (c *Clients) FetchReleaseContract(ctx context.Context, release *Release) error {
+	var verify func([]byte) error
+	if release.TrustedKey != "" {
+		verify = func(payload []byte) error {
+			return c.VerifySignature(ctx, payload, release.ContractSignatureURL, release.TrustedKey)
+		}
+	}
+	parsed, digest, err := c.fetchContract(ctx, release.ContractURL, verify)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to load contract %s from %s: %w", release.ContractURL, release.Version, err)
 	}
-	return versions, nil
+	release.Contract = parsed
+	release.ContractSHA = digest
+	return nil
 }
 
+// FetchContractsFromRepository fetches contracts from a repository.
+func (c *Clients) FetchContractsFromRepository(ctx context.Context, r config.Repository, source Source) (map[string]Release, error) {
+	m := map[string]Release{}
+	releases, err := ListReleases(ctx, r, source)
+	if err != nil {
+		return m, err
+	}
+	for _, release := range releases {
+		if err := c.FetchReleaseContract(ctx, &release); err != nil {
+			return m, err
+		}
+		m[release.Version] = release
+	}
+	return m, nil
+}
+
+// Version is a repository release, as described by the GitHub API, other sources are
+// converting their own representation into it.
 type Version struct {
-	Name       string
-	TagName    string `json:"tag_name"`
-	ID         int    `json:"id"`
-	Draft      bool
-	PreRelease bool
-	Assets     []Asset
-	URL        string `json:"url"`
-	TarballURL string `json:"tarball_url"`
+	Name        string
+	TagName     string `json:"tag_name"`
+	ID          int    `json:"id"`
+	Draft       bool
+	PreRelease  bool
+	Assets      []Asset
+	URL         string    `json:"url"`
+	TarballURL  string    `json:"tarball_url"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Channel returns the channel the release belongs to, stable, prerelease or draft.
+func (v Version) Channel() string {
+	switch {
+	case v.Draft:
+		return ChannelDraft
+	case v.PreRelease:
+		return config.ChannelPreRelease
+	default:
+		return config.ChannelStable
+	}
+}
+
+// inChannel asserts the release is part of the informed channel policy, empty means stable.
+func inChannel(v Version, channel string) bool {
+	switch channel {
+	case config.ChannelAll:
+		return true
+	case config.ChannelPreRelease:
+		return v.Channel() == config.ChannelPreRelease
+	default:
+		return v.Channel() == config.ChannelStable
+	}
 }
 
+// Asset is a file attached to a release.
 type Asset struct {
 	ID          int    `json:"id"`
 	URL         string `json:"url"`
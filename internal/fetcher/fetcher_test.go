@@ -1,13 +1,19 @@
 package fetcher_test
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/google/go-containerregistry/pkg/registry"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
 	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -34,7 +40,11 @@ func TestFetchContractFromRepository(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	m, err := fetcher.FetchContractsFromRepository(repo, client)
+	source, err := fetcher.NewSource(repo, fetcher.NewClients(nil, nil).WithGitHubClient("github.com", client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,3 +52,177 @@ func TestFetchContractFromRepository(t *testing.T) {
 		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
 	}
 }
+
+// gitHubRelease renders a release from the GitHub API with a contract and a tarball asset.
+func gitHubRelease(repo, tag, publishedAt string) string {
+	download := fmt.Sprintf("https://github.com/%s/releases/download/%s", repo, tag)
+	return fmt.Sprintf(`{"tag_name": %q, "published_at": %q, "assets": [
+  {"name": "catalog.yaml", "browser_download_url": "%s/catalog.yaml"},
+  {"name": "resources.tar.gz", "browser_download_url": "%s/resources.tar.gz"}
+]}`, tag, publishedAt, download, download)
+}
+
+func TestFetchContractsFromGitHubPages(t *testing.T) {
+	t.Cleanup(gock.Off)
+
+	repo := config.Repository{
+		Name:                 "golang-task",
+		URL:                  "https://github.com/shortbrain/golang-tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+		MaxReleases:          2,
+		Since:                "2023-01-01",
+	}
+	r := strings.TrimPrefix(repo.URL, "https://github.com/")
+
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s$", r)).
+		Persist().
+		Reply(200).
+		BodyString(`{"private": false}`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases", r)).
+		MatchParam("page", "2").
+		Persist().
+		Reply(200).
+		BodyString(fmt.Sprintf("[%s]", gitHubRelease(r, "v0.1.0", "2022-06-01T00:00:00Z")))
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases", r)).
+		Persist().
+		Reply(200).
+		SetHeader("Link", fmt.Sprintf(`<https://api.github.com/repos/%s/releases?per_page=100&page=2>; rel="next", `+
+			`<https://api.github.com/repos/%s/releases?per_page=100&page=2>; rel="last"`, r, r)).
+		BodyString(fmt.Sprintf("[%s, %s, %s]",
+			gitHubRelease(r, "v1.1.0", "2023-04-01T00:00:00Z"),
+			gitHubRelease(r, "v1.2.0", "2023-06-01T00:00:00Z"),
+			gitHubRelease(r, "v1.0.0", "2023-02-01T00:00:00Z")))
+	for _, tag := range []string{"v1.2.0", "v1.1.0"} {
+		gock.New("https://github.com").
+			Get(fmt.Sprintf("%s/releases/download/%s/catalog.yaml", r, tag)).
+			Reply(200).
+			File("../catalog/testdata/catalog.simple.yaml")
+	}
+
+	client, err := api.NewRESTClient(api.ClientOptions{AuthToken: "fooisbar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := fetcher.NewSource(repo, fetcher.NewClients(nil, nil).WithGitHubClient("github.com", client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := source.Versions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 4 {
+		t.Fatalf("Should have listed 4 versions over 2 pages, got %d: %v", len(versions), versions)
+	}
+
+	m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 {
+		t.Fatalf("Should have fetched the 2 most recent versions, fetched %d: %v", len(m), m)
+	}
+	for _, tag := range []string{"v1.2.0", "v1.1.0"} {
+		if _, ok := m[tag]; !ok {
+			t.Fatalf("Should have fetched %s, got %v", tag, m)
+		}
+	}
+}
+
+func TestFetchContractsFromPrivateGitHub(t *testing.T) {
+	t.Cleanup(gock.Off)
+	t.Setenv("PRIVATE_TASKS_TOKEN", "fooisbar")
+
+	repo := config.Repository{
+		Name:                 "golang-task",
+		URL:                  "https://github.com/shortbrain/private-tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+	r := strings.TrimPrefix(repo.URL, "https://github.com/")
+	assets := fmt.Sprintf("https://api.github.com/repos/%s/releases/assets", r)
+
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s$", r)).
+		Reply(200).
+		BodyString(`{"private": true}`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases", r)).
+		Reply(200).
+		BodyString(fmt.Sprintf(`[{"tag_name": "v1.0.0", "assets": [
+  {"name": "catalog.yaml", "url": "%s/1", "browser_download_url": "https://github.com/%s/releases/download/v1.0.0/catalog.yaml"},
+  {"name": "resources.tar.gz", "url": "%s/2", "browser_download_url": "https://github.com/%s/releases/download/v1.0.0/resources.tar.gz"}
+]}]`, assets, r, assets, r))
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/releases/assets/1", r)).
+		MatchHeader("Accept", "application/octet-stream").
+		MatchHeader("Authorization", "token fooisbar").
+		Reply(200).
+		File("../catalog/testdata/catalog.simple.yaml")
+
+	// the host token authenticates the API requests, and the assets download
+	clients := fetcher.NewClients([]config.Host{{Name: "github.com", TokenEnv: "PRIVATE_TASKS_TOKEN"}}, nil)
+	source, err := fetcher.NewSource(repo, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := clients.FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok {
+		t.Fatalf("Should have fetched v1.0.0, got %v", m)
+	}
+	if release.ResourcesURL != assets+"/2" {
+		t.Fatalf("Should download the resources through the API, got %s", release.ResourcesURL)
+	}
+	if !gock.IsDone() {
+		t.Fatal("Should have downloaded the contract through the API")
+	}
+}
+
+// TestFetchContractsFromOCIWindow asserts the releases window of OCI repositories is applied on
+// the artifacts creation time, not on the registry tags order.
+func TestFetchContractsFromOCIWindow(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	repository := fmt.Sprintf("oci://%s/shortbrain/golang-tasks", strings.TrimPrefix(server.URL, "http://"))
+	for _, tag := range []string{"v0.1.0", "v1.0.0"} {
+		if _, err := oci.Push(context.Background(), repository+":"+tag,
+			"../catalog/testdata/catalog.simple.yaml", "../catalog/testdata/resources.tar.gz"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := config.Repository{
+		URL:                  repository,
+		CatalogName:          "catalog.simple.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+		MaxReleases:          1,
+	}
+	source, err := fetcher.NewSource(repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["v1.0.0"]; !ok || len(m) != 1 {
+		t.Fatalf("Should have fetched the most recent v1.0.0 only, got %v", m)
+	}
+
+	repo.Since = "2999-01-01"
+	m, err = fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 0 {
+		t.Fatalf("Should have ignored the releases published before %s, got %v", repo.Since, m)
+	}
+}
@@ -0,0 +1,97 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// giteaTokenEnv environment variable holding the Gitea (or Forgejo) API token, optional
+	// for public repositories.
+	giteaTokenEnv = "GITEA_TOKEN"
+	// giteaPageLimit amount of releases requested per page.
+	giteaPageLimit = 50
+)
+
+// giteaSource lists releases using the Gitea Releases API, which is also served by Forgejo
+// instances (Codeberg, …).
+type giteaSource struct {
+	apiURL     string       // Gitea instance API endpoint (".../api/v1")
+	repository string       // repository "owner/name"
+	token      string       // Gitea API token
+	client     *http.Client // http client
+}
+
+var _ Source = &giteaSource{}
+
+// Versions lists the repository releases page by page, following the "Link" header. The
+// instance may serve less releases per page than requested (MAX_RESPONSE_ITEMS), so without
+// "Link" header the "X-Total-Count" header is used, and only then an incomplete page is the
+// last one. The Gitea release payload is compatible with the GitHub one, so it's decoded as-is.
+func (g *giteaSource) Versions(ctx context.Context) ([]Version, error) {
+	header := http.Header{}
+	if g.token != "" {
+		header.Set("Authorization", fmt.Sprintf("token %s", g.token))
+	}
+	versions := []Version{}
+	endpoint := g.pageURL(1)
+	for page := 1; endpoint != ""; page++ {
+		releases := []Version{}
+		respHeader, err := getJSON(ctx, g.client, endpoint, header, &releases)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, releases...)
+		endpoint = g.nextPage(respHeader, page, len(releases), len(versions))
+	}
+	return versions, nil
+}
+
+// pageURL returns the endpoint listing the informed page of releases.
+func (g *giteaSource) pageURL(page int) string {
+	return fmt.Sprintf("%s/repos/%s/releases?limit=%d&page=%d", g.apiURL, g.repository, giteaPageLimit, page)
+}
+
+// nextPage returns the endpoint of the page following the informed one, empty when it's the
+// last page. The amount of releases of the page, and listed so far, are used when the response
+// has no "Link" header.
+func (g *giteaSource) nextPage(header http.Header, page, pageSize, listed int) string {
+	if link := header.Get("Link"); link != "" {
+		return nextPageURL(link)
+	}
+	more := pageSize >= giteaPageLimit
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+		more = pageSize > 0 && listed < total
+	}
+	if !more {
+		return ""
+	}
+	return g.pageURL(page + 1)
+}
+
+// newGiteaSource instantiates the source of the Gitea (or Forgejo) repository, the token
+// defaults to the GITEA_TOKEN environment variable.
+func newGiteaSource(repositoryURL, token string, client *http.Client) (*giteaSource, error) {
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL %q: %w", repositoryURL, err)
+	}
+	repository := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if u.Host == "" || strings.Count(repository, "/") != 1 {
+		return nil, fmt.Errorf("invalid Gitea repository URL: %s", repositoryURL)
+	}
+	if token == "" {
+		token = os.Getenv(giteaTokenEnv)
+	}
+	return &giteaSource{
+		apiURL:     fmt.Sprintf("%s://%s/api/v1", u.Scheme, u.Host),
+		repository: repository,
+		token:      token,
+		client:     client,
+	}, nil
+}
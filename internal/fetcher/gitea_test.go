@@ -0,0 +1,176 @@
+package fetcher_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// newGiteaServer starts a Gitea stand-in serving the releases of "owner/golang-tasks".
+func newGiteaServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/api/v1/repos/owner/golang-tasks/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		fmt.Fprintf(w, `[
+  {"tag_name": "v1.2.0", "draft": true, "assets": []},
+  {"tag_name": "v1.1.0", "prerelease": true, "assets": [
+    {"id": 3, "name": "catalog.yaml", "browser_download_url": "%[1]s/owner/golang-tasks/releases/download/v1.1.0/catalog.yaml"},
+    {"id": 4, "name": "resources.tar.gz", "browser_download_url": "%[1]s/owner/golang-tasks/releases/download/v1.1.0/resources.tar.gz"}
+  ]},
+  {"tag_name": "v1.0.0", "assets": [
+    {"id": 1, "name": "catalog.yml", "browser_download_url": "%[1]s/owner/golang-tasks/releases/download/v1.0.0/catalog.yml"},
+    {"id": 2, "name": "resources.tar.gz", "browser_download_url": "%[1]s/owner/golang-tasks/releases/download/v1.0.0/resources.tar.gz"}
+  ]},
+  {"tag_name": "v0.1.0", "assets": []}
+]`, server.URL)
+	})
+	for _, path := range []string{"v1.0.0/catalog.yml", "v1.1.0/catalog.yaml"} {
+		mux.HandleFunc("/owner/golang-tasks/releases/download/"+path, func(w http.ResponseWriter, r *http.Request) {
+			payload, err := os.ReadFile("../catalog/testdata/catalog.simple.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = w.Write(payload)
+		})
+	}
+	return server
+}
+
+func TestFetchContractsFromGitea(t *testing.T) {
+	server := newGiteaServer(t)
+
+	repo := config.Repository{
+		Name:                 "golang-tasks",
+		URL:                  server.URL + "/owner/golang-tasks",
+		Provider:             config.ProviderForgejo,
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+	source, err := fetcher.NewSource(repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok {
+		t.Fatalf("Should have fetched v1.0.0 using the legacy catalog.yml, got %v", m)
+	}
+	if expected := server.URL + "/owner/golang-tasks/releases/download/v1.0.0/resources.tar.gz"; release.ResourcesURL != expected {
+		t.Fatalf("Should have resolved resources URL %s, got %s", expected, release.ResourcesURL)
+	}
+}
+
+func TestFetchContractsChannel(t *testing.T) {
+	server := newGiteaServer(t)
+
+	tests := []struct {
+		channel  string
+		expected map[string]string
+	}{{
+		channel:  "",
+		expected: map[string]string{"v1.0.0": config.ChannelStable},
+	}, {
+		channel:  config.ChannelPreRelease,
+		expected: map[string]string{"v1.1.0": config.ChannelPreRelease},
+	}, {
+		channel:  config.ChannelAll,
+		expected: map[string]string{"v1.0.0": config.ChannelStable, "v1.1.0": config.ChannelPreRelease},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.channel, func(t *testing.T) {
+			repo := config.Repository{
+				URL:                  server.URL + "/owner/golang-tasks",
+				Provider:             config.ProviderGitea,
+				CatalogName:          "catalog.yaml",
+				ResourcesTarballName: "resources.tar.gz",
+				Channel:              tt.channel,
+			}
+			source, err := fetcher.NewSource(repo, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m) != len(tt.expected) {
+				t.Fatalf("Should have fetched %d versions, fetched %d: %v", len(tt.expected), len(m), m)
+			}
+			for tag, channel := range tt.expected {
+				if m[tag].Channel != channel {
+					t.Fatalf("Should have fetched %s on channel %q, got %v", tag, channel, m[tag])
+				}
+			}
+		})
+	}
+}
+
+// TestGiteaPagination lists the releases of an instance serving less releases per page than
+// requested, as capped by MAX_RESPONSE_ITEMS.
+func TestGiteaPagination(t *testing.T) {
+	const total, maxItems = 5, 2
+	for _, tt := range []struct {
+		name string
+		link bool // the instance sets the "Link" header, otherwise only "X-Total-Count"
+	}{{name: "link header", link: true}, {name: "total count"}} {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			mux.HandleFunc("/api/v1/repos/owner/tasks/releases", func(w http.ResponseWriter, r *http.Request) {
+				page, err := strconv.Atoi(r.URL.Query().Get("page"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				w.Header().Set("X-Total-Count", strconv.Itoa(total))
+				if next := page*maxItems + 1; tt.link && next <= total {
+					w.Header().Set("Link", fmt.Sprintf(`<%s%s?limit=%d&page=%d>; rel="next"`,
+						server.URL, r.URL.Path, maxItems, page+1))
+				}
+				fmt.Fprint(w, "[")
+				for i := (page-1)*maxItems + 1; i <= min(page*maxItems, total); i++ {
+					if i > (page-1)*maxItems+1 {
+						fmt.Fprint(w, ",")
+					}
+					fmt.Fprintf(w, `{"tag_name": "v0.%d.0", "assets": []}`, i)
+				}
+				fmt.Fprint(w, "]")
+			})
+
+			source, err := fetcher.NewSource(config.Repository{
+				URL:      server.URL + "/owner/tasks",
+				Provider: config.ProviderGitea,
+			}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			versions, err := source.Versions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(versions) != total {
+				t.Fatalf("Should have listed %d releases, listed %d: %v", total, len(versions), versions)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+// gitHubPageSize amount of releases requested per page, the maximum allowed by the API.
+const gitHubPageSize = 100
+
+// gitHubSource lists releases using the GitHub REST API.
+type gitHubSource struct {
+	repository string          // repository "owner/name"
+	client     *api.RESTClient // GitHub REST API client
+}
+
+var _ Source = &gitHubSource{}
+
+// Versions lists all the repository releases, following the "Link" header pagination.
+func (g *gitHubSource) Versions(ctx context.Context) ([]Version, error) {
+	private, err := g.private(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	next := fmt.Sprintf("repos/%s/releases?per_page=%d", g.repository, gitHubPageSize)
+	for next != "" {
+		page, link, err := g.get(ctx, next, private)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, page...)
+		next = nextPageURL(link)
+	}
+	return versions, nil
+}
+
+// private asserts the repository is not public, either private or internal.
+func (g *gitHubSource) private(ctx context.Context) (bool, error) {
+	repository := struct {
+		Private bool `json:"private"`
+	}{}
+	if err := g.client.DoWithContext(ctx, http.MethodGet, "repos/"+g.repository, nil, &repository); err != nil {
+		return false, err
+	}
+	return repository.Private, nil
+}
+
+// get requests a page of releases, returns the releases and the "Link" header. Assets of
+// private repositories are downloaded through the API.
+func (g *gitHubSource) get(ctx context.Context, path string, private bool) ([]Version, string, error) {
+	resp, err := g.client.RequestWithContext(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	versions := []Version{}
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, "", err
+	}
+	for _, v := range versions {
+		for i, a := range v.Assets {
+			// browser URLs can't be authenticated, assets of private repositories are
+			// downloaded through the API with the host token
+			if private && a.URL != "" {
+				v.Assets[i].DownloadURL = a.URL
+			}
+		}
+	}
+	return versions, resp.Header.Get("Link"), nil
+}
+
+// nextPageURL extracts the URL marked with `rel="next"` from the informed "Link" header,
+// returns empty when there is no next page.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(segments[0]), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// newGitHubSource instantiates the source of the repository on github.com, or on a GitHub
+// Enterprise Server instance, the client must target the repository host.
+func newGitHubSource(repositoryURL string, client *api.RESTClient) (*gitHubSource, error) {
+	if client == nil {
+		return nil, fmt.Errorf("no GitHub client available for %s", repositoryURL)
+	}
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL %q: %w", repositoryURL, err)
+	}
+	repository := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if u.Host == "" || strings.Count(repository, "/") != 1 {
+		return nil, fmt.Errorf("invalid GitHub repository URL: %s", repositoryURL)
+	}
+	return &gitHubSource{
+		repository: repository,
+		client:     client,
+	}, nil
+}
@@ -0,0 +1,169 @@
+package fetcher_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// newGitHubEnterpriseServer serves the releases of "pipelines/tasks" on a fake GitHub Enterprise
+// Server API, requests must be authenticated with the informed token. Returns the transport
+// reaching the server as "example.com".
+func newGitHubEnterpriseServer(t *testing.T, token string, private bool) http.RoundTripper {
+	t.Helper()
+	contract, err := os.ReadFile("../catalog/testdata/catalog.simple.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := "https://example.com/api/v3/repos/pipelines/tasks/releases/assets"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/repos/pipelines/tasks", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"full_name": "pipelines/tasks", "private": %t}`, private)
+	})
+	mux.HandleFunc("/api/v3/repos/pipelines/tasks/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `[{"tag_name": "v1.0.0", "assets": [
+  {"name": "catalog.yaml", "url": "%s/1", "browser_download_url": "https://example.com/pipelines/tasks/releases/download/v1.0.0/catalog.yaml"},
+  {"name": "resources.tar.gz", "url": "%s/2", "browser_download_url": "https://example.com/pipelines/tasks/releases/download/v1.0.0/resources.tar.gz"}
+]}]`, assets, assets)
+	})
+	mux.HandleFunc("/api/v3/repos/pipelines/tasks/releases/assets/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token "+token || r.Header.Get("Accept") != "application/octet-stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(contract) //nolint:errcheck
+	})
+	mux.HandleFunc("/pipelines/tasks/releases/download/v1.0.0/catalog.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if private {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(contract) //nolint:errcheck
+	})
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+
+	// the test certificate is valid for example.com, which is dialed to the server
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	return transport
+}
+
+func TestFetchContractsFromGitHubEnterprise(t *testing.T) {
+	t.Setenv("GHES_TOKEN", "fooisbar")
+	transport := newGitHubEnterpriseServer(t, "fooisbar", true)
+
+	clients := fetcher.NewClients([]config.Host{{
+		Name:     "example.com",
+		Provider: config.ProviderGitHub,
+		TokenEnv: "GHES_TOKEN",
+	}}, transport)
+	repo := config.Repository{
+		URL:                  "https://example.com/pipelines/tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+	source, err := fetcher.NewSource(repo, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := clients.FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok {
+		t.Fatalf("Should have fetched v1.0.0, got %v", m)
+	}
+	if release.Contract == nil {
+		t.Fatal("Should have loaded the contract")
+	}
+	if release.ResourcesURL != "https://example.com/api/v3/repos/pipelines/tasks/releases/assets/2" {
+		t.Fatalf("Should download the resources through the API, got %s", release.ResourcesURL)
+	}
+}
+
+// TestFetchContractsFromPublicGitHubRepository asserts the assets of public repositories are
+// downloaded from the browser URLs, not through the API.
+func TestFetchContractsFromPublicGitHubRepository(t *testing.T) {
+	t.Setenv("GHES_TOKEN", "fooisbar")
+	transport := newGitHubEnterpriseServer(t, "fooisbar", false)
+
+	clients := fetcher.NewClients([]config.Host{{
+		Name:     "example.com",
+		Provider: config.ProviderGitHub,
+		TokenEnv: "GHES_TOKEN",
+	}}, transport)
+	repo := config.Repository{
+		URL:                  "https://example.com/pipelines/tasks",
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+	source, err := fetcher.NewSource(repo, clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := clients.FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok || release.Contract == nil {
+		t.Fatalf("Should have fetched the v1.0.0 contract, got %v", m)
+	}
+	if release.ResourcesURL != "https://example.com/pipelines/tasks/releases/download/v1.0.0/resources.tar.gz" {
+		t.Fatalf("Should download the resources from the browser URL, got %s", release.ResourcesURL)
+	}
+}
+
+func TestGitHubEnterpriseTokenNotSet(t *testing.T) {
+	t.Setenv("GHES_TOKEN", "")
+	clients := fetcher.NewClients([]config.Host{{
+		Name:     "github.example.com",
+		Provider: config.ProviderGitHub,
+		TokenEnv: "GHES_TOKEN",
+	}}, nil)
+	_, err := fetcher.NewSource(config.Repository{URL: "https://github.example.com/pipelines/tasks"}, clients)
+	if err == nil {
+		t.Fatal("Should have errored out on the missing GHES_TOKEN")
+	}
+}
+
+// TestClientsCredentialsIsolation asserts the download credentials are scoped to the clients
+// resolving them, other clients in the same process are not authenticated.
+func TestClientsCredentialsIsolation(t *testing.T) {
+	t.Setenv("GHES_TOKEN", "fooisbar")
+	transport := newGitHubEnterpriseServer(t, "fooisbar", true)
+	const asset = "https://example.com/api/v3/repos/pipelines/tasks/releases/assets/1"
+
+	authenticated := fetcher.NewClients([]config.Host{{
+		Name:     "example.com",
+		Provider: config.ProviderGitHub,
+		TokenEnv: "GHES_TOKEN",
+	}}, transport)
+	if _, err := authenticated.GitHub("example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := authenticated.Digest(context.Background(), asset); err != nil {
+		t.Fatalf("Should have downloaded the asset with the host token, got %v", err)
+	}
+
+	anonymous := fetcher.NewClients(nil, transport)
+	if _, err := anonymous.Digest(context.Background(), asset); err == nil {
+		t.Fatal("Should not have reused the token of other clients")
+	}
+}
@@ -0,0 +1,123 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// gitLabTokenEnv environment variable holding the GitLab API token, optional for public
+// projects.
+const gitLabTokenEnv = "GITLAB_TOKEN"
+
+// gitLabSource lists releases using the GitLab Releases API.
+type gitLabSource struct {
+	apiURL  string       // GitLab instance API endpoint (".../api/v4")
+	project string       // project path, "group/subgroup/name"
+	token   string       // GitLab API token
+	client  *http.Client // http client
+}
+
+var _ Source = &gitLabSource{}
+
+// gitLabRelease is the GitLab representation of a release.
+type gitLabRelease struct {
+	Name            string    `json:"name"`
+	TagName         string    `json:"tag_name"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	ReleasedAt      time.Time `json:"released_at"`
+	Assets          struct {
+		Links []gitLabLink `json:"links"`
+	} `json:"assets"`
+}
+
+// gitLabLink is a release asset link.
+type gitLabLink struct {
+	ID             int    `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+// Versions lists the project releases, following the pagination.
+func (g *gitLabSource) Versions(ctx context.Context) ([]Version, error) {
+	versions := []Version{}
+	page := "1"
+	for page != "" {
+		releases := []gitLabRelease{}
+		next, err := g.get(ctx, fmt.Sprintf("%s/projects/%s/releases?per_page=100&page=%s",
+			g.apiURL, url.PathEscape(g.project), page), &releases)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range releases {
+			versions = append(versions, r.toVersion())
+		}
+		page = next
+	}
+	return versions, nil
+}
+
+// get decodes the JSON response of the informed endpoint, returns the next page.
+func (g *gitLabSource) get(ctx context.Context, endpoint string, v any) (string, error) {
+	header := http.Header{}
+	if g.token != "" {
+		header.Set("PRIVATE-TOKEN", g.token)
+	}
+	respHeader, err := getJSON(ctx, g.client, endpoint, header, v)
+	if err != nil {
+		return "", err
+	}
+	return respHeader.Get("X-Next-Page"), nil
+}
+
+// toVersion converts the GitLab release into a Version, upcoming releases are considered as
+// pre-releases.
+func (r gitLabRelease) toVersion() Version {
+	v := Version{
+		Name:        r.Name,
+		TagName:     r.TagName,
+		PreRelease:  r.UpcomingRelease,
+		PublishedAt: r.ReleasedAt,
+		Assets:      []Asset{},
+	}
+	for _, l := range r.Assets.Links {
+		downloadURL := l.DirectAssetURL
+		if downloadURL == "" {
+			downloadURL = l.URL
+		}
+		v.Assets = append(v.Assets, Asset{
+			ID:          l.ID,
+			URL:         l.URL,
+			Name:        l.Name,
+			DownloadURL: downloadURL,
+		})
+	}
+	return v
+}
+
+// newGitLabSource instantiates the source of the GitLab project, the token defaults to the
+// GITLAB_TOKEN environment variable.
+func newGitLabSource(repositoryURL, token string, client *http.Client) (*gitLabSource, error) {
+	u, err := url.Parse(repositoryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository URL %q: %w", repositoryURL, err)
+	}
+	project := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if u.Host == "" || project == "" {
+		return nil, fmt.Errorf("invalid GitLab project URL: %s", repositoryURL)
+	}
+	if token == "" {
+		token = os.Getenv(gitLabTokenEnv)
+	}
+	return &gitLabSource{
+		apiURL:  fmt.Sprintf("%s://%s/api/v4", u.Scheme, u.Host),
+		project: project,
+		token:   token,
+		client:  client,
+	}, nil
+}
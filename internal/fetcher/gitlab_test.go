@@ -0,0 +1,106 @@
+package fetcher_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// newGitLabServer starts a GitLab stand-in serving the releases of "group/golang-tasks".
+func newGitLabServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/api/v4/projects/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fgolang-tasks/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprintf(w, `[{"tag_name":"v0.9.0","assets":{"links":[]}}]`)
+			return
+		}
+		w.Header().Set("X-Next-Page", "2")
+		fmt.Fprintf(w, `[
+  {"tag_name": "v1.1.0", "upcoming_release": true, "assets": {"links": []}},
+  {"tag_name": "v1.0.0", "assets": {"links": [
+    {"id": 1, "name": "catalog.yaml", "url": "%[1]s/group/golang-tasks/-/releases/v1.0.0/downloads/catalog.yaml"},
+    {"id": 2, "name": "resources.tar.gz", "url": "%[1]s/uploads/resources.tar.gz",
+     "direct_asset_url": "%[1]s/group/golang-tasks/-/releases/v1.0.0/downloads/resources.tar.gz"}
+  ]}}
+]`, server.URL)
+	})
+	mux.HandleFunc("/group/golang-tasks/-/releases/v1.0.0/downloads/catalog.yaml", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := os.ReadFile("../catalog/testdata/catalog.simple.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(payload)
+	})
+	return server
+}
+
+func TestFetchContractsFromGitLab(t *testing.T) {
+	server := newGitLabServer(t)
+
+	repo := config.Repository{
+		Name:                 "golang-tasks",
+		URL:                  server.URL + "/group/golang-tasks",
+		Provider:             config.ProviderGitLab,
+		CatalogName:          "catalog.yaml",
+		ResourcesTarballName: "resources.tar.gz",
+	}
+	source, err := fetcher.NewSource(repo, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := source.Versions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 {
+		t.Fatalf("Should have listed 3 versions over 2 pages, got %d: %v", len(versions), versions)
+	}
+
+	m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 1 {
+		t.Fatalf("Should have fetched only 1 version, fetched %d: %v", len(m), m)
+	}
+	release, ok := m["v1.0.0"]
+	if !ok {
+		t.Fatalf("Should have fetched v1.0.0, got %v", m)
+	}
+	if expected := server.URL + "/group/golang-tasks/-/releases/v1.0.0/downloads/resources.tar.gz"; release.ResourcesURL != expected {
+		t.Fatalf("Should have used the direct asset URL %s, got %s", expected, release.ResourcesURL)
+	}
+	if len(release.Contract.Catalog.Resources.Tasks) != 2 {
+		t.Fatalf("Should have loaded the contract with 2 tasks, got %v", release.Contract.Catalog.Resources.Tasks)
+	}
+}
+
+func TestNewSourceUnsupportedProvider(t *testing.T) {
+	// only the public hosts are inferred, look-alike hosts must configure their provider
+	for _, u := range []string{
+		"https://example.com/foo/bar",
+		"https://notgithub.example.com/foo/bar",
+		"https://gitlab.example.com/foo/bar",
+		"https://my-gitea.example.com/foo/bar",
+	} {
+		_, err := fetcher.NewSource(config.Repository{URL: u}, nil)
+		if err == nil || !strings.Contains(err.Error(), "unknown provider") {
+			t.Fatalf("Should have errored out on %s without a known provider, got %v", u, err)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// getJSON decodes the JSON response of the informed endpoint into "v", using the headers
+// informed, returns the response headers.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, v any) (http.Header, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range header {
+		for _, value := range values {
+			req.Header.Add(k, value)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status error: %v", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, err
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,54 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/oci"
+)
+
+// ociSource lists releases pushed as OCI artifacts, each tag of the repository is a release.
+type ociSource struct {
+	repository string // registry repository, "registry/name"
+}
+
+var _ Source = &ociSource{}
+
+// Versions lists the repository tags, the named layers of each tag artifact are the assets,
+// downloadable using "oci://" blob references. The artifact creation time is the release
+// publication time.
+func (o *ociSource) Versions(ctx context.Context) ([]Version, error) {
+	tags, err := oci.ListTags(ctx, o.repository)
+	if err != nil {
+		return nil, err
+	}
+	versions := []Version{}
+	for _, tag := range tags {
+		artifact, err := oci.Inspect(ctx, fmt.Sprintf("%s:%s", o.repository, tag))
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect tag %s: %w", tag, err)
+		}
+		v := Version{
+			Name:        tag,
+			TagName:     tag,
+			PublishedAt: artifact.Created,
+			Assets:      []Asset{},
+		}
+		for _, l := range artifact.Layers {
+			v.Assets = append(v.Assets, Asset{
+				Name:        l.Name,
+				DownloadURL: oci.BlobReference(o.repository, l.Digest),
+			})
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func newOCISource(repositoryURL string) (*ociSource, error) {
+	repository := oci.TrimScheme(repositoryURL)
+	if repository == "" {
+		return nil, fmt.Errorf("invalid OCI repository: %s", repositoryURL)
+	}
+	return &ociSource{repository: repository}, nil
+}
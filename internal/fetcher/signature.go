@@ -0,0 +1,35 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+)
+
+// ErrSignatureRequired marks a release asset without the detached signature required by the
+// repository, or with a signature not made with the trusted key.
+var ErrSignatureRequired = errors.New("signature required")
+
+// VerifySignature downloads the detached signature on the informed location, and verifies the
+// payload against it using the trusted public key (key file location or inline PEM).
+func (c *Clients) VerifySignature(ctx context.Context, payload []byte, signatureURL, publicKey string) error {
+	if signatureURL == "" {
+		return fmt.Errorf("%w: the release has no detached signature", ErrSignatureRequired)
+	}
+	r, err := c.Open(ctx, signatureURL)
+	if err != nil {
+		return fmt.Errorf("could not download signature %s: %w", signatureURL, err)
+	}
+	defer r.Close()
+	signature, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("could not download signature %s: %w", signatureURL, err)
+	}
+	if err := attestation.VerifyBlob(ctx, publicKey, payload, signature); err != nil {
+		return fmt.Errorf("%w: invalid signature %s: %w", ErrSignatureRequired, signatureURL, err)
+	}
+	return nil
+}
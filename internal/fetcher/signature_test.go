@@ -0,0 +1,142 @@
+package fetcher_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher"
+	"github.com/openshift-pipelines/catalog-cd/internal/fetcher/config"
+)
+
+// staticSource serves the informed versions.
+type staticSource []fetcher.Version
+
+func (s staticSource) Versions(context.Context) ([]fetcher.Version, error) {
+	return s, nil
+}
+
+// newSigningKey generates an ECDSA key, returns the PEM encoded public key and a function
+// signing payloads (base64 signature).
+func newSigningKey(t *testing.T) (string, func([]byte) string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func(payload []byte) string {
+		digest := sha256.Sum256(payload)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(sig)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), sign
+}
+
+func TestFetchContractsRequireSignatures(t *testing.T) {
+	payload, err := os.ReadFile("../catalog/testdata/catalog.simple.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey, sign := newSigningKey(t)
+	otherKey, _ := newSigningKey(t)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	mux.HandleFunc("/catalog.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(payload)
+	})
+	mux.HandleFunc("/catalog.yaml.sig", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sign(payload)))
+	})
+
+	signed := fetcher.Version{TagName: "v1.0.0", Assets: []fetcher.Asset{
+		{Name: "catalog.yaml", DownloadURL: server.URL + "/catalog.yaml"},
+		{Name: "catalog.yaml.sig", DownloadURL: server.URL + "/catalog.yaml.sig"},
+		{Name: "resources.tar.gz", DownloadURL: server.URL + "/resources.tar.gz"},
+		{Name: "resources.tar.gz.sig", DownloadURL: server.URL + "/resources.tar.gz.sig"},
+	}}
+	unsigned := fetcher.Version{TagName: "v1.0.0", Assets: []fetcher.Asset{
+		{Name: "catalog.yaml", DownloadURL: server.URL + "/catalog.yaml"},
+		{Name: "resources.tar.gz", DownloadURL: server.URL + "/resources.tar.gz"},
+	}}
+
+	tests := []struct {
+		name      string
+		version   fetcher.Version
+		publicKey string
+		require   bool
+		err       string
+	}{{
+		name:      "signed",
+		version:   signed,
+		publicKey: publicKey,
+		require:   true,
+	}, {
+		name:      "unsigned, not required",
+		version:   unsigned,
+		publicKey: publicKey,
+	}, {
+		name:      "unsigned",
+		version:   unsigned,
+		publicKey: publicKey,
+		require:   true,
+		err:       "the release has no detached signature",
+	}, {
+		name:      "untrusted key",
+		version:   signed,
+		publicKey: otherKey,
+		require:   true,
+		err:       "invalid signature " + server.URL + "/catalog.yaml.sig",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := config.Repository{
+				URL:                  server.URL + "/owner/tasks",
+				CatalogName:          "catalog.yaml",
+				ResourcesTarballName: "resources.tar.gz",
+				PublicKey:            tt.publicKey,
+				RequireSignatures:    tt.require,
+			}
+			m, err := fetcher.NewClients(nil, nil).FetchContractsFromRepository(context.Background(), repo, staticSource{tt.version})
+			if tt.err != "" {
+				if !errors.Is(err, fetcher.ErrSignatureRequired) {
+					t.Fatalf("Should have required the signature, got %v", err)
+				}
+				if !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Should have errored out with %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			release := m["v1.0.0"]
+			if release.Contract == nil {
+				t.Fatalf("Should have fetched the contract: %v", release)
+			}
+			if len(tt.version.Assets) == len(signed.Assets) &&
+				release.ResourcesSignatureURL != server.URL+"/resources.tar.gz.sig" {
+				t.Fatalf("Should have resolved the tarball signature: %v", release)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package oci distributes catalog releases as OCI artifacts, the contract and the resources
+// tarball are stored as layers annotated with their file names.
+package oci
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const (
+	// Scheme prefix marking a repository, or a blob, hosted on a OCI registry.
+	Scheme = "oci://"
+
+	// ConfigMediaType media type of the catalog release artifact config.
+	ConfigMediaType types.MediaType = "application/vnd.openshift-pipelines.catalog.config.v1+json"
+	// ContractMediaType media type of the layer holding the contract.
+	ContractMediaType types.MediaType = "application/vnd.openshift-pipelines.catalog.contract.v1+yaml"
+	// ResourcesMediaType media type of the layer holding the resources tarball.
+	ResourcesMediaType types.MediaType = "application/vnd.openshift-pipelines.catalog.resources.v1.tar+gzip"
+
+	// annotationTitle standard OCI annotation holding the layer file name.
+	annotationTitle = "org.opencontainers.image.title"
+	// annotationCreated standard OCI annotation holding the artifact creation time (RFC3339).
+	annotationCreated = "org.opencontainers.image.created"
+)
+
+// Artifact is a release pushed on a repository tag.
+type Artifact struct {
+	Created time.Time // artifact creation time, zero when not annotated
+	Layers  []Layer   // named layers, the release files
+}
+
+// Layer is a file stored on the release artifact.
+type Layer struct {
+	Name   string // file name, stored as the layer title
+	Digest string // layer digest
+}
+
+// remoteOptions shared options to interact with registries, uses the docker credentials.
+func remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithContext(ctx),
+	}
+}
+
+// TrimScheme removes the "oci://" prefix from the reference.
+func TrimScheme(reference string) string {
+	return strings.TrimPrefix(reference, Scheme)
+}
+
+// Push uploads the contract and resources tarball files as a OCI artifact on the informed
+// reference, annotated with its creation time, returns the pushed manifest digest.
+func Push(ctx context.Context, reference, contractFile, resourcesFile string) (string, error) {
+	ref, err := name.ParseReference(TrimScheme(reference))
+	if err != nil {
+		return "", err
+	}
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, ConfigMediaType)
+	for _, l := range []struct {
+		file      string
+		mediaType types.MediaType
+	}{
+		{contractFile, ContractMediaType},
+		{resourcesFile, ResourcesMediaType},
+	} {
+		payload, err := os.ReadFile(l.file)
+		if err != nil {
+			return "", err
+		}
+		img, err = mutate.Append(img, mutate.Addendum{
+			Layer:     static.NewLayer(payload, l.mediaType),
+			MediaType: l.mediaType,
+			Annotations: map[string]string{
+				annotationTitle: filepath.Base(l.file),
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	img, ok := mutate.Annotations(img, map[string]string{
+		annotationCreated: time.Now().UTC().Format(time.RFC3339Nano),
+	}).(v1.Image)
+	if !ok {
+		return "", fmt.Errorf("failed to annotate %s", ref)
+	}
+	if err = remote.Write(ref, img, remoteOptions(ctx)...); err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", ref, err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return digest.String(), nil
+}
+
+// ListTags lists the tags of the informed repository.
+func ListTags(ctx context.Context, repository string) ([]string, error) {
+	repo, err := name.NewRepository(TrimScheme(repository))
+	if err != nil {
+		return nil, err
+	}
+	return remote.List(repo, remoteOptions(ctx)...)
+}
+
+// Inspect lists the named layers and the creation time of the artifact on the informed
+// reference.
+func Inspect(ctx context.Context, reference string) (*Artifact, error) {
+	ref, err := name.ParseReference(TrimScheme(reference))
+	if err != nil {
+		return nil, err
+	}
+	img, err := remote.Image(ref, remoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	artifact := &Artifact{Layers: []Layer{}}
+	if created, err := time.Parse(time.RFC3339, manifest.Annotations[annotationCreated]); err == nil {
+		artifact.Created = created
+	}
+	for _, l := range manifest.Layers {
+		title, ok := l.Annotations[annotationTitle]
+		if !ok {
+			continue
+		}
+		artifact.Layers = append(artifact.Layers, Layer{Name: title, Digest: l.Digest.String()})
+	}
+	return artifact, nil
+}
+
+// BlobReference returns the "oci://" reference to the informed blob digest on the repository.
+func BlobReference(repository, digest string) string {
+	return fmt.Sprintf("%s%s@%s", Scheme, TrimScheme(repository), digest)
+}
+
+// OpenBlob opens the blob on the informed digest reference ("registry/repository@sha256:…").
+func OpenBlob(ctx context.Context, reference string) (io.ReadCloser, error) {
+	digest, err := name.NewDigest(TrimScheme(reference))
+	if err != nil {
+		return nil, err
+	}
+	layer, err := remote.Layer(digest, remoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+	return layer.Compressed()
+}
@@ -0,0 +1,212 @@
+// Package provenance describes how a catalog release is built, as an in-toto statement carrying
+// a SLSA provenance predicate, signed into a DSSE envelope.
+package provenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+)
+
+const (
+	// Filename default provenance statement file name, next to the contract.
+	Filename = "provenance.intoto.json"
+	// EnvelopeFilename default signed provenance (DSSE envelope) file name, next to the contract.
+	EnvelopeFilename = "provenance.intoto.jsonl"
+	// StatementType in-toto statement type.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// BuildType describes how the "catalog-cd release" builds the release.
+	BuildType = "https://github.com/openshift-pipelines/catalog-cd/release/v1"
+	// DefaultBuilderID identifies the builder when not informed.
+	DefaultBuilderID = "https://github.com/openshift-pipelines/catalog-cd"
+)
+
+// ErrSubjectMismatch marks a provenance subject doesn't match the released file.
+var ErrSubjectMismatch = errors.New("provenance subject mismatch")
+
+// Statement in-toto statement with the SLSA v1 provenance predicate.
+type Statement = in_toto.ProvenanceStatementSLSA1
+
+// Parameters the release parameters recorded as the build external parameters.
+type Parameters struct {
+	Version string   `json:"version"`
+	Paths   []string `json:"paths"`
+	OCIRef  string   `json:"ociRef,omitempty"`
+}
+
+// Build describes the release execution.
+type Build struct {
+	// BuilderID identifies the entity running the release, DefaultBuilderID when empty.
+	BuilderID string
+	// Parameters release parameters.
+	Parameters Parameters
+	// StartedOn and FinishedOn the release execution window.
+	StartedOn, FinishedOn time.Time
+}
+
+// New creates the provenance statement of the release described by the contract, its subjects
+// are the contract file, the resources tarball and each resource, relative to the contract
+// location.
+func New(c *contract.Contract, contractFile, tarball string, b Build) (*Statement, error) {
+	if b.BuilderID == "" {
+		b.BuilderID = DefaultBuilderID
+	}
+	s := &Statement{
+		StatementHeader: in_toto.StatementHeader{
+			Type:          StatementType,
+			PredicateType: slsa1.PredicateSLSAProvenance,
+			Subject:       []in_toto.Subject{},
+		},
+		Predicate: slsa1.ProvenancePredicate{
+			BuildDefinition: slsa1.ProvenanceBuildDefinition{
+				BuildType:          BuildType,
+				ExternalParameters: b.Parameters,
+			},
+			RunDetails: slsa1.ProvenanceRunDetails{
+				Builder: slsa1.Builder{ID: b.BuilderID},
+				BuildMetadata: slsa1.BuildMetadata{
+					StartedOn:  timeOrNil(b.StartedOn),
+					FinishedOn: timeOrNil(b.FinishedOn),
+				},
+			},
+		},
+	}
+	if err := SetSubject(s, contractFile); err != nil {
+		return nil, err
+	}
+	if err := SetSubject(s, tarball); err != nil {
+		return nil, err
+	}
+	for _, r := range c.Catalog.Resources.All() {
+		s.Subject = append(s.Subject, in_toto.Subject{
+			Name:   filepath.ToSlash(r.Filename),
+			Digest: common.DigestSet{"sha256": r.Checksum},
+		})
+	}
+	return s, nil
+}
+
+// timeOrNil returns nil for the zero time, omitted from the statement.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// SetSubject records the file digest as subject, named after the file base name, an existing
+// subject with the same name is updated.
+func SetSubject(s *Statement, file string) error {
+	sum, err := contract.CalculateSHA256Sum(file)
+	if err != nil {
+		return err
+	}
+	subject := in_toto.Subject{Name: filepath.Base(file), Digest: common.DigestSet{"sha256": sum}}
+	for i := range s.Subject {
+		if s.Subject[i].Name == subject.Name {
+			s.Subject[i] = subject
+			return nil
+		}
+	}
+	s.Subject = append(s.Subject, subject)
+	return nil
+}
+
+// Load reads the provenance statement file.
+func Load(file string) (*Statement, error) {
+	payload, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return decode(payload)
+}
+
+// decode parses the statement, asserting it's a SLSA provenance.
+func decode(payload []byte) (*Statement, error) {
+	s := &Statement{}
+	if err := json.Unmarshal(payload, s); err != nil {
+		return nil, fmt.Errorf("invalid provenance statement: %w", err)
+	}
+	if s.Type != StatementType || s.PredicateType != slsa1.PredicateSLSAProvenance {
+		return nil, fmt.Errorf("invalid provenance statement type %q with predicate %q", s.Type, s.PredicateType)
+	}
+	return s, nil
+}
+
+// Save writes the provenance statement as indented JSON.
+func Save(s *Statement, file string) error {
+	payload, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(payload, '\n'), 0o644)
+}
+
+// Sign signs the provenance statement into a DSSE envelope, written on the informed location.
+func Sign(ctx context.Context, a *attestation.Attestation, s *Statement, envelopeFile string) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	envelope, err := a.SignEnvelope(ctx, in_toto.PayloadType, payload)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(envelopeFile, append(envelope, '\n'), 0o644)
+}
+
+// Verify verifies the DSSE envelope with the candidate keys, offline, and every statement
+// subject digest against the files on the informed directory. Returns the verified statement.
+func Verify(ctx context.Context, keys []string, envelopeFile, dir string) (*Statement, error) {
+	envelope, err := os.ReadFile(envelopeFile)
+	if err != nil {
+		return nil, err
+	}
+	payloadType, payload, err := attestation.VerifyEnvelopeWithKeys(ctx, keys, envelope)
+	if err != nil {
+		return nil, fmt.Errorf("provenance %s: %w", envelopeFile, err)
+	}
+	if payloadType != in_toto.PayloadType {
+		return nil, fmt.Errorf("provenance %s: unexpected payload type %q", envelopeFile, payloadType)
+	}
+	s, err := decode(payload)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	for _, subject := range s.Subject {
+		errs = append(errs, verifySubject(dir, subject))
+	}
+	return s, errors.Join(errs...)
+}
+
+// verifySubject compares the subject sha256 digest with the file on the directory.
+func verifySubject(dir string, subject in_toto.Subject) error {
+	expected, ok := subject.Digest["sha256"]
+	if !ok {
+		return fmt.Errorf("%w: %s has no sha256 digest", ErrSubjectMismatch, subject.Name)
+	}
+	name := filepath.FromSlash(subject.Name)
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("%w: %s is not relative to the release", ErrSubjectMismatch, subject.Name)
+	}
+	sum, err := contract.CalculateSHA256Sum(filepath.Join(dir, name))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrSubjectMismatch, subject.Name, err)
+	}
+	if sum != expected {
+		return fmt.Errorf("%w: %s sha256 %s, expected %s", ErrSubjectMismatch, subject.Name, sum, expected)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package provenance_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/provenance"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+const task = `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+spec:
+  steps:
+    - name: foo
+      image: registry.access.redhat.com/ubi9/ubi-minimal
+`
+
+// newKeyPair generates a cosign key pair on the directory, the password is informed by the
+// environment.
+func newKeyPair(t *testing.T, dir *fs.Dir, name string) (string, string) {
+	t.Helper()
+	t.Setenv("COSIGN_PASSWORD", "secret")
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("secret"), nil })
+	assert.NilError(t, err)
+	privateKey, publicKey := dir.Join(name+".key"), dir.Join(name+".pub")
+	assert.NilError(t, os.WriteFile(privateKey, keys.PrivateBytes, 0o600))
+	assert.NilError(t, os.WriteFile(publicKey, keys.PublicBytes, 0o600))
+	return privateKey, publicKey
+}
+
+func TestProvenance(t *testing.T) {
+	dir := fs.NewDir(t, "provenance",
+		fs.WithDir("tasks", fs.WithDir("foo", fs.WithFile("foo.yaml", task))),
+		fs.WithFile(contract.ResourcesName, "resources"))
+	defer dir.Remove()
+	ctx := context.Background()
+
+	c := contract.NewContractEmpty()
+	assert.NilError(t, c.AddResourceFile(dir.Join("tasks", "foo", "foo.yaml"), "0.0.1"))
+	contractFile := dir.Join(contract.Filename)
+	assert.NilError(t, c.SaveAs(contractFile))
+
+	statement, err := provenance.New(c, contractFile, dir.Join(contract.ResourcesName), provenance.Build{
+		Parameters: provenance.Parameters{Version: "0.0.1", Paths: []string{"tasks"}},
+		StartedOn:  time.Now(),
+		FinishedOn: time.Now(),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, statement.Predicate.RunDetails.Builder.ID, provenance.DefaultBuilderID)
+	names := []string{}
+	for _, s := range statement.Subject {
+		names = append(names, s.Name)
+	}
+	assert.DeepEqual(t, names, []string{contract.Filename, contract.ResourcesName, "tasks/foo/foo.yaml"})
+	assert.Equal(t, statement.Subject[1].Digest["sha256"],
+		"41d311a605520fc7b8b9a980a79437a26e26cebcbfdd569dd78d30f7cb3e7237")
+
+	statementFile := dir.Join(provenance.Filename)
+	assert.NilError(t, provenance.Save(statement, statementFile))
+	statement, err = provenance.Load(statementFile)
+	assert.NilError(t, err)
+
+	privateKey, publicKey := newKeyPair(t, dir, "cosign")
+	_, otherKey := newKeyPair(t, dir, "other")
+	a, err := attestation.NewAttestation(privateKey)
+	assert.NilError(t, err)
+	envelopeFile := dir.Join(provenance.EnvelopeFilename)
+	assert.NilError(t, provenance.Sign(ctx, a, statement, envelopeFile))
+
+	verified, err := provenance.Verify(ctx, []string{otherKey, publicKey}, envelopeFile, dir.Path())
+	assert.NilError(t, err)
+	assert.Equal(t, len(verified.Subject), 3)
+
+	_, err = provenance.Verify(ctx, []string{otherKey}, envelopeFile, dir.Path())
+	assert.ErrorContains(t, err, "provenance")
+
+	// the subjects are checked against the released files
+	assert.NilError(t, os.WriteFile(dir.Join("tasks", "foo", "foo.yaml"), []byte("tampered"), 0o600))
+	_, err = provenance.Verify(ctx, []string{publicKey}, envelopeFile, dir.Path())
+	assert.ErrorIs(t, err, provenance.ErrSubjectMismatch)
+	assert.ErrorContains(t, err, "tasks/foo/foo.yaml sha256")
+}
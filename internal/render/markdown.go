@@ -12,7 +12,8 @@ import (
 	_ "embed"
 )
 
-// Markdown renders a Tekton resource workspaces, params and results as markdown tables.
+// Markdown renders a Tekton resource workspaces, params and results as markdown tables, a
+// StepAction has no workspaces and its results are typed.
 type Markdown struct {
 	cfg *config.Config             // global configuration
 	u   *unstructured.Unstructured // object instance
@@ -21,16 +22,15 @@ type Markdown struct {
 //go:embed tekton.md.tpl
 var markdownTemplate []byte
 
-// templateInputs extracts the inputs for the template.
-func (m *Markdown) templateInputs() (map[string][]interface{}, error) {
-	inputs := map[string][]interface{}{}
-	var err error
-
+// templateInputs extracts the inputs for the template, the resource kind and its attributes.
+func (m *Markdown) templateInputs() (map[string]interface{}, error) {
+	inputs := map[string]interface{}{"kind": m.u.GetKind()}
 	for _, attribute := range []string{"workspaces", "params", "results"} {
-		inputs[attribute], err = linter.GetNestedSlice(m.u, "spec", attribute)
+		slice, err := linter.GetNestedSlice(m.u, "spec", attribute)
 		if err != nil {
 			return nil, err
 		}
+		inputs[attribute] = slice
 	}
 	return inputs, nil
 }
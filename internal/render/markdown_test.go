@@ -1,6 +1,7 @@
 package render
 
 import (
+	"bytes"
 	"testing"
 
 	o "github.com/onsi/gomega"
@@ -19,3 +20,20 @@ func TestNewMarkdow(t *testing.T) {
 	err = m.Render()
 	g.Expect(err).To(o.Succeed())
 }
+
+func TestMarkdownStepAction(t *testing.T) {
+	g := o.NewWithT(t)
+
+	cfg := config.NewConfig()
+	var out bytes.Buffer
+	cfg.Stream.Out = &out
+
+	m, err := NewMarkdown(cfg, "../../testdata/resources/stepaction.yaml")
+	g.Expect(err).To(o.Succeed())
+	g.Expect(m.Render()).To(o.Succeed())
+
+	g.Expect(out.String()).To(o.HavePrefix("## Params"))
+	g.Expect(out.String()).NotTo(o.ContainSubstring("## Workspaces"))
+	g.Expect(out.String()).To(o.ContainSubstring("| `STRING_PARAM_WITH_DEFAULT` | `string` | `default` |"))
+	g.Expect(out.String()).To(o.ContainSubstring("| `ARRAY_RESULT` | `array` | Array result description. |"))
+}
@@ -1,3 +1,4 @@
+{{- if ne .kind "StepAction" -}}
 ## Workspaces
 
 | Workspace      | Optional                           | Description                |
@@ -6,6 +7,7 @@
 | `{{ .name }}`  | `{{ .optional | formatOptional }}` | {{ .description | chomp }} |
 {{- end }}
 
+{{ end -}}
 ## Params
 
 | Param         | Type                       | Default                      | Description                |
@@ -15,9 +17,16 @@
 {{- end }}
 
 ## Results
-
+{{ if eq .kind "StepAction" }}
+| Result        | Type                       | Description                |
+| :------------ | :------------------------: | :------------------------- |
+{{- range .results }}
+| `{{ .name }}` | `{{ .type | formatType }}` | {{ .description | chomp }} |
+{{- end }}
+{{- else }}
 | Result        | Description                |
 | :------------ | :------------------------- |
 {{- range .results }}
 | `{{ .name }}` | {{ .description | chomp }} |
 {{- end }}
+{{- end }}
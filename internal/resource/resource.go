@@ -1,39 +1,95 @@
 package resource
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 
 	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
-// ReadAndDecodeResourceFile reads the informed file and decode contents using Tekton's Kubernetes
-// schema, returning a Unstructured instance.
-func ReadAndDecodeResourceFile(resourceFile string) (*unstructured.Unstructured, error) {
+// Document a YAML document of a resource file, a file may hold several documents separated by
+// "---".
+type Document struct {
+	// Payload the document raw YAML, without the separator.
+	Payload []byte
+	// Object the document as a generic Kubernetes object, not yet validated against the schema,
+	// so unknown kinds are still described by name and kind.
+	Object *unstructured.Unstructured
+}
+
+// ReadDocuments reads the informed file and splits its YAML documents, documents without
+// content (only comments or blank) are ignored.
+func ReadDocuments(resourceFile string) ([]Document, error) {
 	payload, err := os.ReadFile(resourceFile)
 	if err != nil {
 		return nil, err
 	}
+	return SplitDocuments(payload)
+}
+
+// SplitDocuments splits the YAML documents of the payload, see ReadDocuments.
+func SplitDocuments(payload []byte) ([]Document, error) {
+	docs := []Document{}
+	r := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(payload)))
+	for i := 0; ; i++ {
+		doc, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		object := map[string]interface{}{}
+		if err := sigsyaml.Unmarshal(doc, &object); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if len(object) == 0 {
+			continue
+		}
+		docs = append(docs, Document{
+			Payload: doc,
+			Object:  &unstructured.Unstructured{Object: object},
+		})
+	}
+}
 
-	runtimeScheme := runtime.NewScheme()
+// ErrMultipleDocuments marks a payload holding several YAML documents, where a single resource
+// is expected.
+var ErrMultipleDocuments = errors.New("several YAML documents, expects a single resource")
 
-	if err = scheme.AddToScheme(runtimeScheme); err != nil {
-		return nil, err
+// SingleDocument asserts the payload holds at most one YAML document, see SplitDocuments.
+func SingleDocument(payload []byte) error {
+	docs, err := SplitDocuments(payload)
+	if err != nil {
+		return err
 	}
-	if err = v1beta1.AddToScheme(runtimeScheme); err != nil {
-		return nil, err
+	if len(docs) > 1 {
+		return fmt.Errorf("%w: %d documents", ErrMultipleDocuments, len(docs))
 	}
-	if err = v1.AddToScheme(runtimeScheme); err != nil {
+	return nil
+}
+
+// ReadAndDecodeResourceFile reads the informed file and decode contents using Tekton's Kubernetes
+// schema, returning a Unstructured instance. Only the first YAML document is decoded, files
+// holding several documents are split with ReadDocuments.
+func ReadAndDecodeResourceFile(resourceFile string) (*unstructured.Unstructured, error) {
+	payload, err := os.ReadFile(resourceFile)
+	if err != nil {
 		return nil, err
 	}
-
-	obj, _, err := serializer.NewCodecFactory(runtimeScheme).
-		UniversalDeserializer().
-		Decode(payload, nil, nil)
+	obj, err := Decode(payload)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +101,30 @@ func ReadAndDecodeResourceFile(resourceFile string) (*unstructured.Unstructured,
 	return &u, nil
 }
 
+// Decode decodes the resource payload using Tekton's Kubernetes schema, returning the typed
+// instance.
+func Decode(payload []byte) (runtime.Object, error) {
+	runtimeScheme := runtime.NewScheme()
+
+	if err := scheme.AddToScheme(runtimeScheme); err != nil {
+		return nil, err
+	}
+	if err := v1beta1.AddToScheme(runtimeScheme); err != nil {
+		return nil, err
+	}
+	if err := v1.AddToScheme(runtimeScheme); err != nil {
+		return nil, err
+	}
+	if err := v1alpha1.AddToScheme(runtimeScheme); err != nil {
+		return nil, err
+	}
+
+	obj, _, err := serializer.NewCodecFactory(runtimeScheme).
+		UniversalDeserializer().
+		Decode(payload, nil, nil)
+	return obj, err
+}
+
 func GetResourceType(resourceFile string) (string, error) {
 	u, err := ReadAndDecodeResourceFile(resourceFile)
 	if err != nil {
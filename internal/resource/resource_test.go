@@ -0,0 +1,52 @@
+package resource_test
+
+import (
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	docs, err := resource.SplitDocuments([]byte(`# leading comment
+---
+apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+---
+---
+apiVersion: tekton.dev/v1alpha1
+kind: StepAction
+metadata:
+  name: foo-prepare
+---
+# only a comment
+---
+apiVersion: triggers.tekton.dev/v1beta1
+kind: TriggerTemplate
+metadata:
+  name: foo-template
+`))
+	assert.NilError(t, err)
+	assert.Equal(t, len(docs), 3)
+	for i, expected := range [][]string{
+		{"Task", "foo"},
+		{"StepAction", "foo-prepare"},
+		{"TriggerTemplate", "foo-template"},
+	} {
+		assert.Equal(t, docs[i].Object.GetKind(), expected[0])
+		assert.Equal(t, docs[i].Object.GetName(), expected[1])
+	}
+	assert.Equal(t, string(docs[0].Payload), "apiVersion: tekton.dev/v1\nkind: Task\nmetadata:\n  name: foo\n")
+
+	_, err = resource.SplitDocuments([]byte("kind: Task\n---\nkind: [\n"))
+	assert.ErrorContains(t, err, "document 1")
+}
+
+func TestSingleDocument(t *testing.T) {
+	assert.NilError(t, resource.SingleDocument([]byte("---\nkind: Task\n---\n# only a comment\n")))
+	err := resource.SingleDocument([]byte("kind: Task\n---\nkind: Pipeline\n"))
+	assert.ErrorIs(t, err, resource.ErrMultipleDocuments)
+	assert.ErrorContains(t, err, "2 documents")
+}
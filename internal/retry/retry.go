@@ -0,0 +1,317 @@
+// Package retry implements a http.RoundTripper retrying idempotent requests on transient
+// failures with exponential backoff, honoring the "Retry-After" and rate-limit headers sent by
+// GitHub (and compatible forges), and keeping track of the remaining rate-limit per host.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetries amount of retries after the first attempt.
+	DefaultRetries = 4
+	// DefaultBackoff initial backoff, doubled on each retry.
+	DefaultBackoff = 500 * time.Millisecond
+	// DefaultMaxBackoff maximum backoff between two attempts.
+	DefaultMaxBackoff = 30 * time.Second
+	// DefaultMaxWait maximum time waiting for a rate-limit reset, longer waits are failing
+	// right away with RateLimitError.
+	DefaultMaxWait = time.Minute
+)
+
+// RateLimitError reports a request rejected because the rate-limit is exhausted.
+type RateLimitError struct {
+	Host  string    // host enforcing the rate-limit
+	Limit int       // amount of requests allowed on the window, zero when unknown
+	Reset time.Time // rate-limit reset, zero when unknown
+}
+
+// Error describes the rate-limit and when it resets.
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("rate limit exceeded on %s", e.Host)
+	if e.Limit > 0 {
+		msg = fmt.Sprintf("%s (limit of %d requests)", msg, e.Limit)
+	}
+	if !e.Reset.IsZero() {
+		msg = fmt.Sprintf("%s, resets at %s (in %s)",
+			msg, e.Reset.Format(time.Kitchen), time.Until(e.Reset).Round(time.Second))
+	}
+	return msg + ", authenticated requests have a higher limit"
+}
+
+// RateLimit the last rate-limit status reported by a host.
+type RateLimit struct {
+	Host      string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// String renders the rate-limit in a human readable way.
+func (r RateLimit) String() string {
+	s := fmt.Sprintf("%s: %d/%d remaining", r.Host, r.Remaining, r.Limit)
+	if !r.Reset.IsZero() {
+		s = fmt.Sprintf("%s, resets at %s", s, r.Reset.Format(time.Kitchen))
+	}
+	return s
+}
+
+// Transport is a http.RoundTripper retrying idempotent requests (GET, HEAD and OPTIONS) on
+// network errors, server errors (5xx) and rate-limited responses (429, or 403 with an exhausted
+// rate-limit). The delay between attempts grows exponentially, unless the server informs when
+// to retry using "Retry-After" or "X-RateLimit-Reset".
+type Transport struct {
+	Retries    int           // amount of retries after the first attempt
+	Backoff    time.Duration // initial backoff, doubled on each retry
+	MaxBackoff time.Duration // maximum backoff between two attempts
+	MaxWait    time.Duration // maximum time waiting for a rate-limit reset
+
+	next http.RoundTripper // transport used to reach the network
+
+	mu         sync.Mutex
+	retries    int                   // amount of retries so far
+	rateLimits map[string]*RateLimit // last rate-limit seen, per host
+}
+
+var _ http.RoundTripper = &Transport{}
+
+// New instantiates the transport with the default settings on top of the informed transport,
+// when nil http.DefaultTransport is used.
+func New(next http.RoundTripper) *Transport {
+	return &Transport{
+		Retries:    DefaultRetries,
+		Backoff:    DefaultBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		MaxWait:    DefaultMaxWait,
+		next:       next,
+		rateLimits: map[string]*RateLimit{},
+	}
+}
+
+// transport returns the next transport, resolving the default transport on demand.
+func (t *Transport) transport() http.RoundTripper {
+	if t.next != nil {
+		return t.next
+	}
+	return http.DefaultTransport
+}
+
+// Retried returns the amount of retries so far.
+func (t *Transport) Retried() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.retries
+}
+
+// RateLimits returns the last rate-limit status reported by each host, sorted by host.
+func (t *Transport) RateLimits() []RateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	limits := make([]RateLimit, 0, len(t.rateLimits))
+	for _, r := range t.rateLimits {
+		limits = append(limits, *r)
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i].Host < limits[j].Host })
+	return limits
+}
+
+// idempotent asserts the request can be sent again safely.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+// RoundTrip sends the request, retrying idempotent requests on transient failures.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !idempotent(req) {
+		return t.transport().RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.transport().RoundTrip(r)
+		if resp != nil {
+			t.observe(req.URL.Host, resp.Header)
+		}
+		if req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay, retry, rerr := t.classify(req, resp, err, attempt)
+		if rerr != nil {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, rerr
+		}
+		if !retry || attempt >= t.Retries {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		t.mu.Lock()
+		t.retries++
+		t.mu.Unlock()
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// classify decides whether the attempt should be retried and after which delay. An error is
+// returned when the rate-limit is exhausted for longer than the maximum wait.
+func (t *Transport) classify(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool, error) {
+	backoff := t.backoff(attempt)
+	if err != nil {
+		return backoff, !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded), nil
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusForbidden && rateLimited(resp.Header):
+		if attempt >= t.Retries {
+			return 0, false, t.rateLimitError(req.URL.Host, resp.Header)
+		}
+		if d, ok := retryAfter(resp.Header); ok {
+			if d > t.MaxWait {
+				return 0, false, t.rateLimitError(req.URL.Host, resp.Header)
+			}
+			return d, true, nil
+		}
+		if reset, ok := rateLimitReset(resp.Header); ok && remaining(resp.Header) == 0 {
+			d := time.Until(reset) + time.Second
+			if d > t.MaxWait {
+				return 0, false, t.rateLimitError(req.URL.Host, resp.Header)
+			}
+			return max(d, 0), true, nil
+		}
+		return backoff, true, nil
+	case resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented:
+		if d, ok := retryAfter(resp.Header); ok && d <= t.MaxWait {
+			return d, true, nil
+		}
+		return backoff, true, nil
+	}
+	return 0, false, nil
+}
+
+// backoff returns the exponential backoff for the attempt, with up to 20% of jitter to avoid
+// concurrent requests retrying in lockstep.
+func (t *Transport) backoff(attempt int) time.Duration {
+	if t.Backoff <= 0 {
+		return 0
+	}
+	d := t.Backoff << attempt
+	if d <= 0 || (t.MaxBackoff > 0 && d > t.MaxBackoff) {
+		// overflow or above the maximum backoff
+		d = t.MaxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1)) //nolint:gosec
+}
+
+// rateLimitError builds the error out of the rate-limit headers.
+func (t *Transport) rateLimitError(host string, header http.Header) error {
+	e := &RateLimitError{Host: host}
+	e.Limit, _ = strconv.Atoi(rateLimitHeader(header, "Limit"))
+	e.Reset, _ = rateLimitReset(header)
+	return e
+}
+
+// observe records the rate-limit headers sent by the host, when present.
+func (t *Transport) observe(host string, header http.Header) {
+	limit, err := strconv.Atoi(rateLimitHeader(header, "Limit"))
+	if err != nil {
+		return
+	}
+	r := &RateLimit{Host: host, Limit: limit, Remaining: remaining(header)}
+	r.Reset, _ = rateLimitReset(header)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rateLimits == nil {
+		t.rateLimits = map[string]*RateLimit{}
+	}
+	t.rateLimits[host] = r
+}
+
+// rateLimitHeader returns the rate-limit header, GitHub and Gitea are using the "X-RateLimit-"
+// prefix while GitLab uses "RateLimit-".
+func rateLimitHeader(header http.Header, name string) string {
+	if v := header.Get("X-RateLimit-" + name); v != "" {
+		return v
+	}
+	return header.Get("RateLimit-" + name)
+}
+
+// rateLimited asserts the response is caused by an exhausted rate-limit, either the primary
+// rate-limit (no requests remaining) or a secondary one (with "Retry-After").
+func rateLimited(header http.Header) bool {
+	return rateLimitHeader(header, "Remaining") == "0" || header.Get("Retry-After") != ""
+}
+
+// remaining returns the amount of requests remaining, -1 when unknown.
+func remaining(header http.Header) int {
+	n, err := strconv.Atoi(rateLimitHeader(header, "Remaining"))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// rateLimitReset returns the rate-limit reset time, sent as an epoch in seconds.
+func rateLimitReset(header http.Header) (time.Time, bool) {
+	epoch, err := strconv.ParseInt(rateLimitHeader(header, "Reset"), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(epoch, 0), true
+}
+
+// retryAfter parses the "Retry-After" header, either a delay in seconds or a HTTP date.
+func retryAfter(header http.Header) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for the informed delay, unless the context is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,166 @@
+package retry_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/retry"
+	"gotest.tools/v3/assert"
+)
+
+// newTransport instantiates the transport without delays between attempts.
+func newTransport() *retry.Transport {
+	t := retry.New(nil)
+	t.Backoff = time.Millisecond
+	t.MaxBackoff = 10 * time.Millisecond
+	return t
+}
+
+// failing serves the informed failures before succeeding.
+func failing(t *testing.T, failures ...func(http.ResponseWriter)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(60-n))
+		if n <= len(failures) {
+			failures[n-1](w)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func status(code int) func(http.ResponseWriter) {
+	return func(w http.ResponseWriter) { w.WriteHeader(code) }
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) (*http.Response, error) {
+	t.Helper()
+	client := &http.Client{Transport: transport}
+	resp, err := client.Get(url) // nolint:noctx
+	if err != nil {
+		return nil, err
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp, nil
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	srv, requests := failing(t, status(http.StatusBadGateway), status(http.StatusServiceUnavailable))
+	transport := newTransport()
+
+	resp, err := get(t, transport, srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	payload, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	assert.Equal(t, string(payload), "ok")
+	assert.Equal(t, requests.Load(), int32(3))
+	assert.Equal(t, transport.Retried(), 2)
+
+	limits := transport.RateLimits()
+	assert.Equal(t, len(limits), 1)
+	assert.Equal(t, limits[0].Limit, 60)
+	assert.Equal(t, limits[0].Remaining, 57)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, requests := failing(t,
+		status(http.StatusBadGateway), status(http.StatusBadGateway), status(http.StatusBadGateway))
+	transport := newTransport()
+	transport.Retries = 1
+
+	resp, err := get(t, transport, srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, requests.Load(), int32(2))
+}
+
+func TestRetryClientErrors(t *testing.T) {
+	srv, requests := failing(t, status(http.StatusNotFound))
+
+	resp, err := get(t, newTransport(), srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusNotFound)
+	assert.Equal(t, requests.Load(), int32(1))
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	srv, requests := failing(t, status(http.StatusBadGateway))
+
+	client := &http.Client{Transport: newTransport()}
+	resp, err := client.Post(srv.URL, "text/plain", nil) // nolint:noctx
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusBadGateway)
+	assert.Equal(t, requests.Load(), int32(1))
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, requests := failing(t, func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, func(w http.ResponseWriter) {
+		// secondary rate-limit
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	resp, err := get(t, newTransport(), srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, requests.Load(), int32(3))
+}
+
+func TestRetryRateLimitReset(t *testing.T) {
+	srv, requests := failing(t, func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	start := time.Now()
+	resp, err := get(t, newTransport(), srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, requests.Load(), int32(2))
+	assert.Assert(t, time.Since(start) < 3*time.Second)
+}
+
+func TestRetryRateLimitExceeded(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	srv, requests := failing(t, func(w http.ResponseWriter) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := get(t, newTransport(), srv.URL)
+	var rateLimitErr *retry.RateLimitError
+	assert.Assert(t, errors.As(err, &rateLimitErr), "unexpected error: %v", err)
+	assert.Equal(t, rateLimitErr.Limit, 60)
+	assert.Equal(t, rateLimitErr.Reset.Unix(), reset.Unix())
+	assert.ErrorContains(t, err, "rate limit exceeded")
+	assert.Equal(t, requests.Load(), int32(1))
+}
+
+func TestRetryForbidden(t *testing.T) {
+	// forbidden without rate-limit exhausted, not retried
+	srv, requests := failing(t, status(http.StatusForbidden))
+
+	resp, err := get(t, newTransport(), srv.URL)
+	assert.NilError(t, err)
+	assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+	assert.Equal(t, requests.Load(), int32(1))
+}
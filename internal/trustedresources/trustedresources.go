@@ -0,0 +1,221 @@
+// Package trustedresources signs Tekton resources the way Tekton Pipelines trusted resources
+// verifies them, the signature is embedded on the resource "tekton.dev/signature" annotation.
+package trustedresources
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/resource"
+
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"gopkg.in/yaml.v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// SignatureAnnotation annotation holding the base64 encoded resource signature.
+const SignatureAnnotation = "tekton.dev/signature"
+
+// ErrNotSigned marks the resource doesn't carry the signature annotation.
+var ErrNotSigned = errors.New("resource has no embedded signature")
+
+// excludedAnnotations annotations added by other components, not part of the signed payload.
+var excludedAnnotations = []string{
+	SignatureAnnotation,
+	"kubectl-client-side-apply",
+	"kubectl.kubernetes.io/last-applied-configuration",
+}
+
+// Digest returns the SHA256 digest of the resource as signed by Tekton, the JSON encoded typed
+// resource with only the user managed metadata, and without the signature annotation. The
+// embedded signature is returned as well, nil when the resource isn't signed.
+func Digest(payload []byte) ([]byte, []byte, error) {
+	// the unsupported versions are rejected before decoding, they are not part of the schema
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(payload, &u.Object); err != nil {
+		return nil, nil, err
+	}
+	if err := contract.IsResourceSupported(u); err != nil {
+		return nil, nil, err
+	}
+	obj, err := resource.Decode(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	o, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported resource %T", obj)
+	}
+	meta := metav1.ObjectMeta{
+		Name:         o.GetName(),
+		GenerateName: o.GetGenerateName(),
+		Namespace:    o.GetNamespace(),
+		Labels:       o.GetLabels(),
+		Annotations:  map[string]string{},
+	}
+	for k, v := range o.GetAnnotations() {
+		meta.Annotations[k] = v
+	}
+	var signature []byte
+	if sig, ok := meta.Annotations[SignatureAnnotation]; ok {
+		if signature, err = base64.StdEncoding.DecodeString(sig); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s annotation: %w", SignatureAnnotation, err)
+		}
+	}
+	for _, k := range excludedAnnotations {
+		delete(meta.Annotations, k)
+	}
+
+	var signed interface{}
+	switch r := obj.(type) {
+	case *v1.Task:
+		signed = &v1.Task{TypeMeta: typeMeta(v1.SchemeGroupVersion.String(), "Task"), ObjectMeta: meta, Spec: r.Spec}
+	case *v1.Pipeline:
+		signed = &v1.Pipeline{TypeMeta: typeMeta(v1.SchemeGroupVersion.String(), "Pipeline"), ObjectMeta: meta, Spec: r.Spec}
+	case *v1beta1.Task:
+		signed = &v1beta1.Task{TypeMeta: typeMeta(v1beta1.SchemeGroupVersion.String(), "Task"), ObjectMeta: meta, Spec: r.Spec}
+	case *v1beta1.Pipeline:
+		signed = &v1beta1.Pipeline{TypeMeta: typeMeta(v1beta1.SchemeGroupVersion.String(), "Pipeline"), ObjectMeta: meta, Spec: r.Spec}
+	case *v1alpha1.StepAction:
+		signed = &v1alpha1.StepAction{TypeMeta: typeMeta(v1alpha1.SchemeGroupVersion.String(), "StepAction"), ObjectMeta: meta, Spec: r.Spec}
+	default:
+		return nil, nil, fmt.Errorf("unsupported resource %T", obj)
+	}
+	b, err := json.Marshal(signed)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], signature, nil
+}
+
+// typeMeta returns the resource type metadata.
+func typeMeta(apiVersion, kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{APIVersion: apiVersion, Kind: kind}
+}
+
+// Sign signs the resource file with the attestation private key, and embeds the signature on
+// the resource annotations, overwriting a previous signature. The resource file is rewritten.
+func Sign(ctx context.Context, a *attestation.Attestation, file string) error {
+	payload, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	digest, _, err := Digest(payload)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	signature, err := a.SignMessage(ctx, digest)
+	if err != nil {
+		return err
+	}
+	payload, err = embed(payload, base64.StdEncoding.EncodeToString(signature))
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return os.WriteFile(file, payload, 0o644) // nolint: gosec
+}
+
+// Signed asserts the resource file carries an embedded signature.
+func Signed(file string) (bool, error) {
+	payload, err := os.ReadFile(file)
+	if err != nil {
+		return false, err
+	}
+	_, signature, err := Digest(payload)
+	return signature != nil, err
+}
+
+// Verify verifies the resource file embedded signature with the candidate keys, and the
+// annotations expected on the resource once the signature is verified.
+func Verify(ctx context.Context, keys []string, annotations map[string]string, file string) error {
+	payload, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return verify(ctx, keys, annotations, file, payload)
+}
+
+// VerifyPayload verifies the resource embedded signature, as Verify.
+func VerifyPayload(ctx context.Context, keys []string, annotations map[string]string, payload []byte) error {
+	return verify(ctx, keys, annotations, "resource", payload)
+}
+
+// verify verifies the embedded signature and the annotations, the name identifies the resource.
+func verify(ctx context.Context, keys []string, annotations map[string]string, name string, payload []byte) error {
+	digest, signature, err := Digest(payload)
+	if err != nil {
+		return err
+	}
+	if signature == nil {
+		return ErrNotSigned
+	}
+	if err := attestation.VerifyMessageWithKeys(ctx, keys, digest, signature); err != nil {
+		return err
+	}
+	return attestation.VerifyAnnotations(name, payload, annotations)
+}
+
+// embed sets the signature annotation on the resource YAML, preserving the document layout.
+func embed(payload []byte, signature string) ([]byte, error) {
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(payload, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("resource is not a YAML object")
+	}
+	metadata := mappingValue(doc.Content[0], "metadata")
+	annotations := mappingValue(metadata, "annotations")
+	setMappingValue(annotations, SignatureAnnotation, signature)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the mapping under the key, created when missing.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			v := m.Content[i+1]
+			if v.Kind != yaml.MappingNode {
+				*v = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			return v
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+	return v
+}
+
+// setMappingValue sets the string value under the key.
+func setMappingValue(m *yaml.Node, key, value string) {
+	v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = v
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
@@ -0,0 +1,166 @@
+package trustedresources_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openshift-pipelines/catalog-cd/internal/attestation"
+	"github.com/openshift-pipelines/catalog-cd/internal/contract"
+	"github.com/openshift-pipelines/catalog-cd/internal/trustedresources"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	v1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const task = `apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: foo
+  labels:
+    app.kubernetes.io/version: "0.1"
+  annotations:
+    team: tekton-ecosystem
+spec:
+  # the step prints the greeting
+  steps:
+    - name: foo
+      image: registry.access.redhat.com/ubi9/ubi-minimal
+      script: echo hello
+`
+
+// newKeyPair generates a cosign key pair on the directory, the password is informed by the
+// environment.
+func newKeyPair(t *testing.T, dir *fs.Dir, name string) (string, string) {
+	t.Helper()
+	t.Setenv("COSIGN_PASSWORD", "secret")
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("secret"), nil })
+	assert.NilError(t, err)
+	privateKey, publicKey := dir.Join(name+".key"), dir.Join(name+".pub")
+	assert.NilError(t, os.WriteFile(privateKey, keys.PrivateBytes, 0o600))
+	assert.NilError(t, os.WriteFile(publicKey, keys.PublicBytes, 0o600))
+	return privateKey, publicKey
+}
+
+func TestSignAndVerify(t *testing.T) {
+	dir := fs.NewDir(t, "trustedresources", fs.WithFile("task.yaml", task))
+	defer dir.Remove()
+	ctx := context.Background()
+	file := dir.Join("task.yaml")
+
+	privateKey, publicKey := newKeyPair(t, dir, "cosign")
+	_, otherKey := newKeyPair(t, dir, "other")
+
+	err := trustedresources.Verify(ctx, []string{publicKey}, nil, file)
+	assert.ErrorIs(t, err, trustedresources.ErrNotSigned)
+
+	a, err := attestation.NewAttestation(privateKey)
+	assert.NilError(t, err)
+	assert.NilError(t, trustedresources.Sign(ctx, a, file))
+
+	payload, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(payload), trustedresources.SignatureAnnotation+": "))
+	assert.Assert(t, strings.Contains(string(payload), "# the step prints the greeting"))
+	signed, err := trustedresources.Signed(file)
+	assert.NilError(t, err)
+	assert.Assert(t, signed)
+
+	assert.NilError(t, trustedresources.Verify(ctx, []string{otherKey, publicKey},
+		map[string]string{"team": "tekton-ecosystem"}, file))
+	err = trustedresources.Verify(ctx, []string{otherKey}, nil, file)
+	assert.ErrorContains(t, err, "invalid signature")
+	err = trustedresources.Verify(ctx, []string{publicKey}, map[string]string{"team": "other"}, file)
+	assert.ErrorIs(t, err, attestation.ErrAnnotationMismatch)
+
+	// signing again replaces the signature, the document stays valid
+	assert.NilError(t, trustedresources.Sign(ctx, a, file))
+	assert.NilError(t, trustedresources.VerifyPayload(ctx, []string{publicKey}, nil, mustRead(t, file)))
+
+	// the signature covers the resource spec
+	tampered := strings.Replace(string(mustRead(t, file)), "echo hello", "echo tampered", 1)
+	err = trustedresources.VerifyPayload(ctx, []string{publicKey}, nil, []byte(tampered))
+	assert.ErrorContains(t, err, "invalid signature")
+}
+
+func TestSignAndVerifyStepAction(t *testing.T) {
+	dir := fs.NewDir(t, "trustedresources", fs.WithFile("stepaction.yaml", `apiVersion: tekton.dev/v1alpha1
+kind: StepAction
+metadata:
+  name: foo
+spec:
+  image: registry.access.redhat.com/ubi9/ubi-minimal
+  script: echo hello
+`))
+	defer dir.Remove()
+	ctx := context.Background()
+	file := dir.Join("stepaction.yaml")
+
+	privateKey, publicKey := newKeyPair(t, dir, "cosign")
+	a, err := attestation.NewAttestation(privateKey)
+	assert.NilError(t, err)
+	assert.NilError(t, trustedresources.Sign(ctx, a, file))
+	assert.NilError(t, trustedresources.Verify(ctx, []string{publicKey}, nil, file))
+
+	tampered := strings.Replace(string(mustRead(t, file)), "echo hello", "echo tampered", 1)
+	err = trustedresources.VerifyPayload(ctx, []string{publicKey}, nil, []byte(tampered))
+	assert.ErrorContains(t, err, "invalid signature")
+
+	// StepAction is only supported as v1alpha1
+	v1beta1 := strings.Replace(string(mustRead(t, file)), "tekton.dev/v1alpha1", "tekton.dev/v1beta1", 1)
+	_, _, err = trustedresources.Digest([]byte(v1beta1))
+	assert.ErrorIs(t, err, contract.ErrTektonResourceUnsupported)
+	assert.ErrorContains(t, err, `unsupported version "v1beta1" for StepAction (expects v1alpha1)`)
+}
+
+// TestVerifyTektonSignature verifies a signature computed as Tekton Pipelines does, over the
+// SHA256 digest of the JSON encoded typed resource without the signature annotation.
+func TestVerifyTektonSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	obj := v1.Task{
+		TypeMeta: metav1.TypeMeta{APIVersion: "tekton.dev/v1", Kind: "Task"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "foo",
+			Labels:      map[string]string{"app.kubernetes.io/version": "0.1"},
+			Annotations: map[string]string{"team": "tekton-ecosystem"},
+		},
+		Spec: v1.TaskSpec{Steps: []v1.Step{{
+			Name:   "foo",
+			Image:  "registry.access.redhat.com/ubi9/ubi-minimal",
+			Script: "echo hello",
+		}}},
+	}
+	b, err := json.Marshal(obj)
+	assert.NilError(t, err)
+	h := sha256.Sum256(b)
+	digest := sha256.Sum256(h[:])
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NilError(t, err)
+
+	signed := strings.Replace(task, "    team: tekton-ecosystem\n",
+		"    team: tekton-ecosystem\n    tekton.dev/signature: "+base64.StdEncoding.EncodeToString(sig)+"\n", 1)
+	assert.NilError(t, trustedresources.VerifyPayload(context.Background(), []string{publicKey}, nil, []byte(signed)))
+}
+
+func mustRead(t *testing.T, file string) []byte {
+	t.Helper()
+	payload, err := os.ReadFile(file)
+	assert.NilError(t, err)
+	return payload
+}